@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/reload"
+)
+
+// AdminToken is the bearer token of the admin routes, which are disabled while
+// it is empty.
+var AdminToken = ""
+
+type ReloadResponse struct {
+	Weapons     int  `json:"weapons"`
+	Projectiles int  `json:"projectiles"`
+	Enemies     int  `json:"enemies"`
+	TickRate    uint `json:"tick_rate"`
+}
+
+func checkAdminToken(r *http.Request) error {
+	if len(AdminToken) == 0 {
+		return errs.Forbidden("admin api is disabled")
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1 {
+		return errs.Unauthorized("invalid admin token")
+	}
+	return nil
+}
+
+func HandleReloadDefinitions(w http.ResponseWriter, r *http.Request) {
+	if err := checkAdminToken(r); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	set, err := reload.Reload(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ReloadResponse{
+		Weapons:     len(set.Weapons),
+		Projectiles: len(set.Projectiles),
+		Enemies:     len(set.Enemies),
+		TickRate:    set.Tuning.TickRate,
+	})
+}
@@ -2,12 +2,10 @@ package api
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 	"net/http"
-	"strconv"
 	"strings"
 	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/errs"
 	"valley-of-survival-dawn-of-squares/internal/game"
 	"valley-of-survival-dawn-of-squares/internal/session"
 	"valley-of-survival-dawn-of-squares/internal/utils"
@@ -21,30 +19,33 @@ type Credentials struct {
 }
 
 type ClanPayload struct {
-	Name     string `json:"name"`
-	Password string `json:"password"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+	JoinPolicy string `json:"join_policy,omitempty"`
 }
 
 var VosDosSessionToken = "vosdos-session-token"
 
+var errNotLoggedIn = errs.Unauthorized("user not logged in")
+
 func HandlerWithAuth(handlerFunc http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sessionID := r.Header.Get(VosDosSessionToken)
 		if len(sessionID) == 0 {
-			http.Error(w, "user not logged in", http.StatusBadRequest)
+			writeError(w, errNotLoggedIn)
 			return
 		}
 
 		username, exists := session.GetUsername(sessionID)
 		if !exists {
-			http.Error(w, "invalid session token", http.StatusBadRequest)
+			writeError(w, errs.Unauthorized("invalid session token"))
 			return
 		}
 
 		clientId, exists := utils.GetClientSession(sessionID)
 
 		if !exists || strings.Compare(strings.TrimSpace(clientId), strings.TrimSpace(utils.GetClientIdentifier(r))) != 0 {
-			http.Error(w, "invalid session token", http.StatusBadRequest)
+			writeError(w, errs.Unauthorized("invalid session token"))
 			return
 		}
 
@@ -58,43 +59,68 @@ func HandlerWithAuth(handlerFunc http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// getCurrentUser returns the user of the request's session.
+func getCurrentUser(r *http.Request) (*game.User, error) {
+	session, ok := utils.GetSession(r)
+	if !ok {
+		return nil, errNotLoggedIn
+	}
+
+	return db.GetUserByName(r.Context(), session.Username)
+}
+
+// getCurrentPlayer returns the player of the request's session.
+func getCurrentPlayer(r *http.Request) (*game.Player, error) {
+	session, ok := utils.GetSession(r)
+	if !ok {
+		return nil, errNotLoggedIn
+	}
+
+	return db.GetPlayerByUsername(r.Context(), session.Username)
+}
+
 func HandleVerifySession(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
 func HandleSignup(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	var creds Credentials
+	if err := decodeJSON(r, &creds); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	var creds Credentials
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if len(creds.Username) == 0 || len(creds.Password) == 0 {
+		writeError(w, errs.Validation("username and password are required"))
 		return
 	}
 
 	if _, err := db.GetUserByName(r.Context(), creds.Username); err == nil {
-		http.Error(w, "user already exists", http.StatusConflict)
+		writeError(w, errs.Conflict("user already exists"))
+		return
+	} else if errs.KindOf(err) != errs.KindNotFound {
+		writeError(w, err)
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, errs.Internal(err))
 		return
 	}
 
 	if err := db.CreateUser(r.Context(), creds.Username, string(hashedPassword)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	// New players have no run in progress, their first spawn starts one in
+	// a spawn area of the map
 	if user, err := db.GetUserByName(r.Context(), creds.Username); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
-	} else if err = db.CreatePlayer(r.Context(), user.ID, 100, [2]uint{game.HalfWorldSize, game.HalfWorldSize}, "white", "", 0); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else if err = db.CreatePlayer(r.Context(), user.ID, 0, [2]int{game.HalfWorldSize, game.HalfWorldSize}, "white", "", 0); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -102,22 +128,21 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var creds Credentials
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := decodeJSON(r, &creds); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	if user, err := db.GetUserByName(r.Context(), creds.Username); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	invalidCredentials := errs.Unauthorized("invalid credentials")
+	if user, err := db.GetUserByName(r.Context(), creds.Username); errs.KindOf(err) == errs.KindNotFound {
+		writeError(w, invalidCredentials)
+		return
+	} else if err != nil {
+		writeError(w, err)
 		return
 	} else if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)) != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		writeError(w, invalidCredentials)
 		return
 	}
 
@@ -143,358 +168,218 @@ func HandleLogout(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleGetUserInfo(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
 	var user *game.User
-	var err error
-
-	userIDStr := r.URL.Query().Get("user_id")
 
-	if len(userIDStr) != 0 {
-		userID, err := strconv.Atoi(userIDStr)
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		user, err = db.GetUserByID(r.Context(), uint(userID))
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else if username := queryParams.Get("username"); len(username) != 0 {
-		user, err = db.GetUserByName(r.Context(), username)
-	} else {
-		http.Error(w, "no username nor user_id query paramter given", http.StatusBadRequest)
+	userID, ok, err := parseIDParam(r, "user_id")
+	if err != nil {
+		writeError(w, err)
 		return
-
 	}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if ok {
+		user, err = db.GetUserByID(r.Context(), userID)
+	} else if username := r.URL.Query().Get("username"); len(username) != 0 {
+		user, err = db.GetUserByName(r.Context(), username)
+	} else {
+		writeError(w, errs.Validation("no username nor user_id query paramter given"))
 		return
 	}
 
-	userJson, err := json.Marshal(*user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(userJson)
+	writeJSON(w, http.StatusOK, *user)
 }
 
 func HandleGetPlayerInfo(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
 	var player *game.Player
-	var err error
-
-	playerIDStr := r.URL.Query().Get("player_id")
-
-	if len(playerIDStr) != 0 {
-		playerID, err := strconv.Atoi(playerIDStr)
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		player, err = db.GetPlayerByID(r.Context(), uint(playerID))
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else if username := queryParams.Get("username"); len(username) != 0 {
-		player, err = db.GetPlayerByUsername(r.Context(), username)
-	} else {
-		http.Error(w, "no username nor player_id query paramter given", http.StatusBadRequest)
+	playerID, ok, err := parseIDParam(r, "player_id")
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if ok {
+		player, err = db.GetPlayerByID(r.Context(), playerID)
+	} else if username := r.URL.Query().Get("username"); len(username) != 0 {
+		player, err = db.GetPlayerByUsername(r.Context(), username)
+	} else {
+		writeError(w, errs.Validation("no username nor player_id query paramter given"))
 		return
 	}
 
-	playerJson, err := json.Marshal(*player)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(playerJson)
+	writeJSON(w, http.StatusOK, *player)
 }
 
 func HandleGetClanInfo(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
 	var clan *game.Clan
-	var err error
-
-	if clandIDStr := queryParams.Get("clan_id"); len(clandIDStr) != 0 {
-		clanID, err := strconv.Atoi(clandIDStr)
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clan, err = db.GetClanByID(r.Context(), uint(clanID))
+	clanID, ok, err := parseIDParam(r, "clan_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if ok {
+		clan, err = db.GetClanByID(r.Context(), clanID)
 	} else if clanName := r.URL.Query().Get("clan_name"); len(clanName) != 0 {
 		clan, err = db.GetClanByName(r.Context(), clanName)
 	} else {
-		http.Error(w, "no clan_name query paramter given", http.StatusBadRequest)
+		writeError(w, errs.Validation("no clan_name query paramter given"))
 		return
 	}
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	clanJson, err := json.Marshal(*clan)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write(clanJson)
+	writeJSON(w, http.StatusOK, *clan)
 }
 
 func HandleGetWeaponClassInfo(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	var weaponClass *game.WeaponClass
-	var err error
-
-	weaponClassIDStr := queryParams.Get("weapon_class_id")
-
-	if len(weaponClassIDStr) != 0 {
-		weaponClassID, err := strconv.Atoi(weaponClassIDStr)
-
-		if err != nil {
-			http.Error(w, "invalid weapon_class_id", http.StatusBadRequest)
-			return
-		}
-
-		weaponClass, err = db.GetWeaponClass(r.Context(), uint(weaponClassID))
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		http.Error(w, "no weapon_class_id query parameter given", http.StatusBadRequest)
+	weaponClassID, ok, err := parseIDParam(r, "weapon_class_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	} else if !ok {
+		writeError(w, errs.Validation("no weapon_class_id query parameter given"))
 		return
 	}
 
-	weaponClassJson, err := json.Marshal(*weaponClass)
+	weaponClass, err := db.GetWeaponClass(r.Context(), weaponClassID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(weaponClassJson)
+	writeJSON(w, http.StatusOK, *weaponClass)
 }
 
 func HandleGetWeaponInfo(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	var weapon *game.Weapon
-	var err error
-
-	weaponIDStr := queryParams.Get("weapon_id")
-
-	if len(weaponIDStr) != 0 {
-		weaponID, err := strconv.Atoi(weaponIDStr)
-
-		if err != nil {
-			http.Error(w, "invalid weapon_id", http.StatusBadRequest)
-			return
-		}
-
-		weapon, err = db.GetWeapon(r.Context(), uint(weaponID))
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		http.Error(w, "no weapon_id query parameter given", http.StatusBadRequest)
+	weaponID, ok, err := parseIDParam(r, "weapon_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	} else if !ok {
+		writeError(w, errs.Validation("no weapon_id query parameter given"))
 		return
 	}
 
-	weaponJson, err := json.Marshal(*weapon)
+	weapon, err := db.GetWeapon(r.Context(), weaponID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(weaponJson)
+	writeJSON(w, http.StatusOK, *weapon)
 }
 
 func HandleGetCurrentUserInfo(w http.ResponseWriter, r *http.Request) {
-	session, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
-	}
-
-	user, err := db.GetUserByName(r.Context(), session.Username)
+	user, err := getCurrentUser(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	userJson, err := json.Marshal(*user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write(userJson)
+	writeJSON(w, http.StatusOK, *user)
 }
 
 func HandleGetCurrentPlayerInfo(w http.ResponseWriter, r *http.Request) {
-	session, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
-	}
-
-	player, err := db.GetPlayerByUsername(r.Context(), session.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	playerJson, err := json.Marshal(*player)
+	player, err := getCurrentPlayer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(playerJson)
+	writeJSON(w, http.StatusOK, *player)
 }
 
 func HandleGetCurrentClanInfo(w http.ResponseWriter, r *http.Request) {
 	session, ok := utils.GetSession(r)
 	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
+		writeError(w, errNotLoggedIn)
 		return
 	}
 
 	clan, err := db.GetClanByUsername(r.Context(), session.Username)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusExpectationFailed)
-		return
-	}
-
-	if clan == nil {
-		log.Printf("Warning: no clan found for user %s", session.Username)
-		http.Error(w, "clan not found", http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 
-	clanJson, err := json.Marshal(*clan)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write(clanJson)
+	writeJSON(w, http.StatusOK, *clan)
 }
 
 func HandleGetCurrentWeaponsInfo(w http.ResponseWriter, r *http.Request) {
-	session, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
-	}
-
-	player, err := db.GetPlayerByUsername(r.Context(), session.Username)
+	player, err := getCurrentPlayer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	weapons, err := db.GetPlayerWeapons(r.Context(), player.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	weaponsJson, err := json.Marshal(weapons)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(weaponsJson)
+	writeJSON(w, http.StatusOK, weapons)
 }
 
 func HandleCreateClan(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	player, err := getCurrentPlayer(r)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	sessionToken, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
+	if player.ClanID != nil {
+		writeError(w, errs.Conflict("user is already in a clan"))
 		return
 	}
 
-	user, err := db.GetUserByName(r.Context(), sessionToken.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var clanPayload ClanPayload
+	if err := decodeJSON(r, &clanPayload); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	player, err := db.GetPlayerByUsername(r.Context(), user.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if len(clanPayload.Name) == 0 {
+		writeError(w, errs.Validation("clan name is required"))
 		return
 	}
 
-	if player.ClanID != nil {
-		http.Error(w, "user is already in a clan", http.StatusBadRequest)
-		return
+	if len(clanPayload.JoinPolicy) == 0 {
+		clanPayload.JoinPolicy = game.ClanPolicyClosed
 	}
 
-	var clanPayload ClanPayload
-	if err := json.NewDecoder(r.Body).Decode(&clanPayload); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !isValidClanPolicy(clanPayload.JoinPolicy) {
+		writeError(w, errs.Validation("invalid join policy"))
 		return
 	}
 
-	if err := db.CreateClan(r.Context(), clanPayload.Name, clanPayload.Password, user.ID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if clanPayload.JoinPolicy == game.ClanPolicyClosed && len(clanPayload.Password) == 0 {
+		writeError(w, errs.Validation("closed clans require a password"))
 		return
 	}
 
-	clan, err := db.GetClanByName(r.Context(), clanPayload.Name)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(clanPayload.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, errs.Internal(err))
 		return
 	}
 
-	if err := db.JoinClan(r.Context(), user.ID, clan.ID); err != nil {
-		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := db.CreateClan(r.Context(), clanPayload.Name, string(hashedPassword), player.UserID, clanPayload.JoinPolicy); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -502,36 +387,14 @@ func HandleCreateClan(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleDeleteClan(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	sessionToken, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
-	}
-
-	user, err := db.GetUserByName(r.Context(), sessionToken.Username)
+	_, clan, err := getCurrentClan(r, game.ClanRoleOwner)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	clan, err := db.GetClanByUsername(r.Context(), user.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if clan.OwnerID != user.ID {
-		http.Error(w, "user is not the owner of the clan", http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	if err := db.DeleteClan(r.Context(), clan.ID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -539,53 +402,57 @@ func HandleDeleteClan(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleJoinClan(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	invite := r.URL.Query().Get("invite")
 
 	var clanPayload ClanPayload
-	if err := json.NewDecoder(r.Body).Decode(&clanPayload); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	sessionToken, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
+	if len(invite) == 0 {
+		if err := decodeJSON(r, &clanPayload); err != nil {
+			writeError(w, err)
+			return
+		}
 	}
 
-	user, err := db.GetUserByName(r.Context(), sessionToken.Username)
+	player, err := getCurrentPlayer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	player, err := db.GetPlayerByUsername(r.Context(), user.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if player.ClanID != nil {
+		writeError(w, errs.Conflict("user is already in a clan"))
 		return
 	}
 
-	if player.ClanID != nil {
-		http.Error(w, "user is already in a clan", http.StatusBadRequest)
+	if len(invite) != 0 {
+		if _, err := db.JoinClanWithInvite(r.Context(), player.UserID, invite); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	clan, err := db.GetClanByName(r.Context(), clanPayload.Name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	if clan.Password != clanPayload.Password {
-		http.Error(w, "invalid password", http.StatusBadRequest)
+	switch clan.JoinPolicy {
+	case game.ClanPolicyOpen:
+	case game.ClanPolicyClosed:
+		if bcrypt.CompareHashAndPassword([]byte(clan.Password), []byte(clanPayload.Password)) != nil {
+			writeError(w, errs.Forbidden("invalid password"))
+			return
+		}
+	default:
+		writeError(w, errs.Forbidden("clan is invite only"))
 		return
 	}
 
-	if err := db.JoinClan(r.Context(), user.ID, clan.ID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := db.JoinClan(r.Context(), player.UserID, clan.ID, game.ClanRoleMember); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -593,47 +460,36 @@ func HandleJoinClan(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleLeaveClan(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	sessionToken, ok := utils.GetSession(r)
-	if !ok {
-		http.Error(w, "user not logged in", http.StatusBadRequest)
-		return
-	}
-
-	user, err := db.GetUserByName(r.Context(), sessionToken.Username)
+	player, clan, err := getCurrentClan(r, game.ClanRoleMember)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	player, err := db.GetPlayerByUsername(r.Context(), user.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	if clan.OwnerID == player.UserID {
+		members, err := db.GetClanPlayers(r.Context(), clan.ID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
 
-	if player.ClanID == nil {
-		http.Error(w, "user is not in a clan", http.StatusBadRequest)
-		return
-	}
+		if len(members) > 1 {
+			writeError(w, errs.Conflict("transfer ownership of the clan before leaving it"))
+			return
+		}
 
-	clan, err := db.GetClanByUsername(r.Context(), user.Username)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+		// The owner is the last member, leaving disbands the clan
+		if err := db.DeleteClan(r.Context(), clan.ID); err != nil {
+			writeError(w, err)
+			return
+		}
 
-	if clan.OwnerID == user.ID {
-		http.Error(w, "user is the owner of the clan", http.StatusBadRequest)
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	if err := db.LeaveClan(r.Context(), user.ID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := db.LeaveClan(r.Context(), player.UserID); err != nil {
+		writeError(w, err)
 		return
 	}
 
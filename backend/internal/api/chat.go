@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"valley-of-survival-dawn-of-squares/internal/chat"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+type ChatIgnorePayload struct {
+	Username string `json:"username"`
+}
+
+func HandleGetChatHistory(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	channel := queryParams.Get("channel")
+	if len(channel) == 0 {
+		channel = game.ChatChannelGlobal
+	}
+
+	limit := chat.DefaultHistoryLimit
+	if limitStr := queryParams.Get("limit"); len(limitStr) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 || limit > chat.MaxHistoryLimit {
+			writeError(w, errs.Validation("limit must be between 1 and "+strconv.Itoa(chat.MaxHistoryLimit)))
+			return
+		}
+	}
+
+	player, err := getCurrentPlayer(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var clanID *uint
+	switch channel {
+	case game.ChatChannelGlobal, game.ChatChannelDirect:
+	case game.ChatChannelClan:
+		if player.ClanID == nil {
+			writeError(w, errs.NotFound("user is not in a clan"))
+			return
+		}
+		clanID = player.ClanID
+	default:
+		writeError(w, errs.Validation("invalid chat channel"))
+		return
+	}
+
+	messages, err := db.GetChatHistory(r.Context(), player.UserID, channel, clanID, uint(limit))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+func HandleGetChatIgnores(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ignores, err := db.GetChatIgnores(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ignores)
+}
+
+// handleChatIgnore adds or removes a mute or block of the user named in the
+// request body for the logged in user.
+func handleChatIgnore(w http.ResponseWriter, r *http.Request, kind string, ignore bool) {
+	var ignorePayload ChatIgnorePayload
+	if err := decodeJSON(r, &ignorePayload); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := getCurrentUser(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	target, err := db.GetUserByName(r.Context(), ignorePayload.Username)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if target.ID == user.ID {
+		writeError(w, errs.Validation("cannot target yourself"))
+		return
+	}
+
+	if ignore {
+		err = db.SetChatIgnore(r.Context(), user.ID, target.ID, kind)
+	} else {
+		err = db.RemoveChatIgnore(r.Context(), user.ID, target.ID, kind)
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleMuteUser(w http.ResponseWriter, r *http.Request) {
+	handleChatIgnore(w, r, game.ChatIgnoreMute, true)
+}
+
+func HandleUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	handleChatIgnore(w, r, game.ChatIgnoreMute, false)
+}
+
+func HandleBlockUser(w http.ResponseWriter, r *http.Request) {
+	handleChatIgnore(w, r, game.ChatIgnoreBlock, true)
+}
+
+func HandleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	handleChatIgnore(w, r, game.ChatIgnoreBlock, false)
+}
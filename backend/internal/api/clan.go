@@ -0,0 +1,217 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+type ClanMemberPayload struct {
+	PlayerID uint `json:"player_id"`
+}
+
+var clanRoleRanks = map[string]int{
+	game.ClanRoleMember:  1,
+	game.ClanRoleOfficer: 2,
+	game.ClanRoleOwner:   3,
+}
+
+// getCurrentClan resolves the logged in user's player and clan, failing if
+// the player is not in a clan or their role ranks below minRole.
+func getCurrentClan(r *http.Request, minRole string) (*game.Player, *game.Clan, error) {
+	player, err := getCurrentPlayer(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if player.ClanID == nil {
+		return nil, nil, errs.NotFound("user is not in a clan")
+	}
+
+	clan, err := db.GetClanByID(r.Context(), *player.ClanID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if clanRoleRanks[player.ClanRole] < clanRoleRanks[minRole] {
+		return nil, nil, errs.Forbidden("user is not allowed to do this in the clan").
+			WithDetails(map[string]any{"required_role": minRole})
+	}
+
+	return player, clan, nil
+}
+
+// getClanTarget decodes a ClanMemberPayload and resolves the targeted player,
+// who must be another member of the given clan.
+func getClanTarget(r *http.Request, actor *game.Player, clan *game.Clan) (*game.Player, error) {
+	var memberPayload ClanMemberPayload
+	if err := decodeJSON(r, &memberPayload); err != nil {
+		return nil, err
+	}
+
+	if memberPayload.PlayerID == actor.ID {
+		return nil, errs.Validation("cannot target yourself")
+	}
+
+	target, err := db.GetPlayerByID(r.Context(), memberPayload.PlayerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.ClanID == nil || *target.ClanID != clan.ID {
+		return nil, errs.Validation("player is not a member of the clan")
+	}
+
+	return target, nil
+}
+
+func HandleGetClanMembers(w http.ResponseWriter, r *http.Request) {
+	clanID, ok, err := parseIDParam(r, "clan_id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !ok {
+		_, clan, err := getCurrentClan(r, game.ClanRoleMember)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		clanID = clan.ID
+	}
+
+	members, err := db.GetClanPlayers(r.Context(), clanID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, members)
+}
+
+func HandleKickClanMember(w http.ResponseWriter, r *http.Request) {
+	player, clan, err := getCurrentClan(r, game.ClanRoleOfficer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	target, err := getClanTarget(r, player, clan)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if clanRoleRanks[target.ClanRole] >= clanRoleRanks[player.ClanRole] {
+		writeError(w, errs.Forbidden("cannot kick a member of equal or higher rank"))
+		return
+	}
+
+	if err := db.KickClanMember(r.Context(), clan.ID, player.UserID, target.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandlePromoteClanMember(w http.ResponseWriter, r *http.Request) {
+	player, clan, err := getCurrentClan(r, game.ClanRoleOwner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	target, err := getClanTarget(r, player, clan)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if target.ClanRole != game.ClanRoleMember {
+		writeError(w, errs.Validation("only members can be promoted, use transfer to change the owner"))
+		return
+	}
+
+	if err := db.SetClanRole(r.Context(), clan.ID, player.UserID, target.ID, game.ClanRoleOfficer, game.ClanActionPromote); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleDemoteClanMember(w http.ResponseWriter, r *http.Request) {
+	player, clan, err := getCurrentClan(r, game.ClanRoleOwner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	target, err := getClanTarget(r, player, clan)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if target.ClanRole != game.ClanRoleOfficer {
+		writeError(w, errs.Validation("only officers can be demoted"))
+		return
+	}
+
+	if err := db.SetClanRole(r.Context(), clan.ID, player.UserID, target.ID, game.ClanRoleMember, game.ClanActionDemote); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleTransferClanOwnership(w http.ResponseWriter, r *http.Request) {
+	player, clan, err := getCurrentClan(r, game.ClanRoleOwner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	target, err := getClanTarget(r, player, clan)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := db.TransferClanOwnership(r.Context(), clan.ID, player.UserID, target.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleGetClanAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 || limit > 200 {
+			writeError(w, errs.Validation("limit must be between 1 and 200"))
+			return
+		}
+	}
+
+	_, clan, err := getCurrentClan(r, game.ClanRoleOfficer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	entries, err := db.GetClanAuditLog(r.Context(), clan.ID, uint(limit))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
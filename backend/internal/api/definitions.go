@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+func HandleGetWeaponDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Weapons)
+}
+
+func HandleGetEvolutionDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Evolutions)
+}
+
+func HandleGetProjectileDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Projectiles)
+}
+
+func HandleGetEnemyDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Enemies)
+}
+
+func HandleGetBossDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Bosses)
+}
+
+func HandleGetPassiveDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Passives)
+}
+
+func HandleGetDifficultyDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Difficulties)
+}
+
+func HandleGetCurseDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Curses)
+}
+
+func HandleGetTuningDefinitions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, definitions.Current().Tuning)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+)
+
+type ErrorResponse struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+var errorStatuses = map[string]int{
+	errs.KindValidation:       http.StatusBadRequest,
+	errs.KindUnauthorized:     http.StatusUnauthorized,
+	errs.KindForbidden:        http.StatusForbidden,
+	errs.KindNotFound:         http.StatusNotFound,
+	errs.KindMethodNotAllowed: http.StatusMethodNotAllowed,
+	errs.KindConflict:         http.StatusConflict,
+	errs.KindTooLarge:         http.StatusRequestEntityTooLarge,
+	errs.KindInternal:         http.StatusInternalServerError,
+}
+
+// writeError maps err to its status code and writes it as an ErrorResponse.
+// Internal errors are logged and replaced by a generic message.
+func writeError(w http.ResponseWriter, err error) {
+	e := errs.As(err)
+
+	status, ok := errorStatuses[e.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	response := ErrorResponse{Code: e.Kind, Message: e.Message, Details: e.Details}
+	if status == http.StatusInternalServerError {
+		log.Println("Internal error:", err)
+		response = ErrorResponse{Code: errs.KindInternal, Message: "internal server error"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, errs.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return errs.New(errs.KindTooLarge, "request body is too large").
+				WithDetails(map[string]any{"limit": maxBytesErr.Limit})
+		}
+		return errs.Validation("invalid request body").WithDetails(map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// parseIDParam parses the query parameter name as an ID, returning ok false
+// if it is not given.
+func parseIDParam(r *http.Request, name string) (uint, bool, error) {
+	str := r.URL.Query().Get(name)
+	if len(str) == 0 {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(str, 10, 0)
+	if err != nil {
+		return 0, true, errs.Validation("invalid " + name)
+	}
+
+	return uint(id), true, nil
+}
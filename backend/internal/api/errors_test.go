@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"validation", errs.Validation("bad name"), http.StatusBadRequest, errs.KindValidation, "bad name"},
+		{"unauthorized", errs.Unauthorized("invalid session token"), http.StatusUnauthorized, errs.KindUnauthorized, "invalid session token"},
+		{"forbidden", errs.Forbidden("not an officer"), http.StatusForbidden, errs.KindForbidden, "not an officer"},
+		{"not found", errs.NotFound("clan not found"), http.StatusNotFound, errs.KindNotFound, "clan not found"},
+		{"method not allowed", errs.MethodNotAllowed(), http.StatusMethodNotAllowed, errs.KindMethodNotAllowed, "method not allowed"},
+		{"conflict", errs.Conflict("already exists"), http.StatusConflict, errs.KindConflict, "already exists"},
+		{"too large", errs.New(errs.KindTooLarge, "request body is too large"), http.StatusRequestEntityTooLarge, errs.KindTooLarge, "request body is too large"},
+		{"internal hides its cause", errs.Internal(errors.New("secret")), http.StatusInternalServerError, errs.KindInternal, "internal server error"},
+		{"plain error is internal", errors.New("secret"), http.StatusInternalServerError, errs.KindInternal, "internal server error"},
+		{"unknown kind is internal", errs.New("teapot", "short and stout"), http.StatusInternalServerError, errs.KindInternal, "internal server error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeError(recorder, test.err)
+
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d", recorder.Code, test.status)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Code != test.code || response.Message != test.message {
+				t.Errorf("response = %+v, want code %q and message %q", response, test.code, test.message)
+			}
+		})
+	}
+}
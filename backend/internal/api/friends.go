@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/friends"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+type FriendPayload struct {
+	Username string `json:"username"`
+}
+
+// getFriendUsers resolves the logged in user and the other user with the
+// given name.
+func getFriendUsers(r *http.Request, username string) (*game.User, *game.User, error) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	other, err := db.GetUserByName(r.Context(), username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if other.ID == user.ID {
+		return nil, nil, errs.Validation("cannot target yourself")
+	}
+
+	return user, other, nil
+}
+
+// decodeFriendUsers decodes a FriendPayload and resolves its users.
+func decodeFriendUsers(r *http.Request) (*game.User, *game.User, error) {
+	var friendPayload FriendPayload
+	if err := decodeJSON(r, &friendPayload); err != nil {
+		return nil, nil, err
+	}
+
+	return getFriendUsers(r, friendPayload.Username)
+}
+
+func HandleGetFriends(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	list, err := friends.GetFriendList(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *list)
+}
+
+func HandleSendFriendRequest(w http.ResponseWriter, r *http.Request) {
+	user, other, err := decodeFriendUsers(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	accepted, err := db.CreateFriendRequest(r.Context(), user.ID, other.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if accepted {
+		go friends.PushPresenceTo(user.Username, other.Username)
+		go friends.PushPresenceTo(other.Username, user.Username)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	friends.NotifyFriendRequest(other.Username, game.Friend{UserID: user.ID, Username: user.Username})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func HandleAcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	user, other, err := decodeFriendUsers(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := db.AcceptFriendRequest(r.Context(), user.ID, other.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	go friends.PushPresenceTo(user.Username, other.Username)
+	go friends.PushPresenceTo(other.Username, user.Username)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleDeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	user, other, err := decodeFriendUsers(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := db.DeclineFriendRequest(r.Context(), user.ID, other.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleRemoveFriend(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if len(username) == 0 {
+		writeError(w, errs.Validation("no username query parameter given"))
+		return
+	}
+
+	user, other, err := getFriendUsers(r, username)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := db.RemoveFriend(r.Context(), user.ID, other.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
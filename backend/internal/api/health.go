@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+// MaxTickAge is how long the world may go without ticking before the server
+// is reported as not ready.
+var MaxTickAge = 2 * time.Second
+
+type ReadinessResponse struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"` // "ok" or the reason the check failed
+}
+
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	response := ReadinessResponse{Ready: true, Checks: make(map[string]string)}
+	fail := func(check string, reason string) {
+		response.Ready = false
+		response.Checks[check] = reason
+	}
+
+	c, cancel := context.WithTimeout(r.Context(), time.Second)
+	defer cancel()
+	if err := db.Ping(c); err != nil {
+		fail("db", "unreachable")
+	} else {
+		response.Checks["db"] = "ok"
+	}
+
+	lastTick, duration := game.LastTick()
+	switch {
+	case lastTick.IsZero():
+		fail("world", "not started")
+	case time.Since(lastTick) > MaxTickAge:
+		fail("world", "not ticking")
+	case duration > game.TickInterval():
+		fail("world", "tick over budget: "+duration.String())
+	default:
+		response.Checks["world"] = "ok"
+	}
+
+	status := http.StatusOK
+	if !response.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ClanInvitePayload struct {
+	MaxUses   uint `json:"max_uses"`   // 0 means unlimited uses
+	ExpiresIn uint `json:"expires_in"` // seconds, 0 means no expiry
+}
+
+type ClanSettingsPayload struct {
+	JoinPolicy string  `json:"join_policy"`
+	Password   *string `json:"password,omitempty"`
+}
+
+func isValidClanPolicy(policy string) bool {
+	switch policy {
+	case game.ClanPolicyOpen, game.ClanPolicyClosed, game.ClanPolicyInviteOnly:
+		return true
+	}
+	return false
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func HandleCreateClanInvite(w http.ResponseWriter, r *http.Request) {
+	player, clan, err := getCurrentClan(r, game.ClanRoleOfficer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var invitePayload ClanInvitePayload
+	if err := decodeJSON(r, &invitePayload); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if invitePayload.MaxUses == 0 && invitePayload.ExpiresIn == 0 {
+		writeError(w, errs.Validation("invite must be limited by max_uses or expires_in"))
+		return
+	}
+
+	var usesLeft *uint
+	if invitePayload.MaxUses != 0 {
+		usesLeft = &invitePayload.MaxUses
+	}
+
+	var expiresAt *time.Time
+	if invitePayload.ExpiresIn != 0 {
+		t := time.Now().Add(time.Duration(invitePayload.ExpiresIn) * time.Second)
+		expiresAt = &t
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		writeError(w, errs.Internal(err))
+		return
+	}
+
+	invite, err := db.CreateClanInvite(r.Context(), clan.ID, player.UserID, code, usesLeft, expiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *invite)
+}
+
+func HandleGetClanInvites(w http.ResponseWriter, r *http.Request) {
+	_, clan, err := getCurrentClan(r, game.ClanRoleOfficer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	invites, err := db.GetClanInvites(r.Context(), clan.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invites)
+}
+
+func HandleRevokeClanInvite(w http.ResponseWriter, r *http.Request) {
+	invite := r.URL.Query().Get("invite")
+	if len(invite) == 0 {
+		writeError(w, errs.Validation("no invite query parameter given"))
+		return
+	}
+
+	_, clan, err := getCurrentClan(r, game.ClanRoleOfficer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := db.RevokeClanInvite(r.Context(), clan.ID, invite); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func HandleUpdateClanSettings(w http.ResponseWriter, r *http.Request) {
+	_, clan, err := getCurrentClan(r, game.ClanRoleOwner)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var settingsPayload ClanSettingsPayload
+	if err := decodeJSON(r, &settingsPayload); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !isValidClanPolicy(settingsPayload.JoinPolicy) {
+		writeError(w, errs.Validation("invalid join policy"))
+		return
+	}
+
+	var hashedPassword *string
+	if settingsPayload.Password != nil {
+		if len(*settingsPayload.Password) == 0 {
+			writeError(w, errs.Validation("password cannot be empty"))
+			return
+		}
+
+		hashed, err := bcrypt.GenerateFromPassword([]byte(*settingsPayload.Password), bcrypt.DefaultCost)
+		if err != nil {
+			writeError(w, errs.Internal(err))
+			return
+		}
+		hashedString := string(hashed)
+		hashedPassword = &hashedString
+	} else if settingsPayload.JoinPolicy == game.ClanPolicyClosed && clan.JoinPolicy != game.ClanPolicyClosed {
+		writeError(w, errs.Validation("closed clans require a password"))
+		return
+	}
+
+	if err := db.UpdateClanSettings(r.Context(), clan.ID, settingsPayload.JoinPolicy, hashedPassword); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
+)
+
+// Middleware wraps a handler with additional behaviour.
+type Middleware func(next http.Handler) http.Handler
+
+// Chain wraps handler with the middlewares, the first one being the outermost.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+var RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// GetRequestID returns the ID assigned to the request by the RequestID middleware.
+func GetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// statusRecorder remembers the status code written by a handler. It keeps
+// supporting hijacking so websocket upgrades work through the middlewares.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	s.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// RequestID assigns every request an ID, reusing the one sent by the client
+// if present, and echoes it in the response headers.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if len(requestID) == 0 || len(requestID) > 64 {
+			b := make([]byte, 8)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		log.Printf("[%s] %s %s %d %s", GetRequestID(r), r.Method, r.URL.Path, recorder.status, time.Since(start))
+	})
+}
+
+var requestDurations = metrics.NewHistogram("http_request_duration_seconds", "Time spent serving HTTP requests.", metrics.DefaultBuckets, "method", "route", "status")
+
+// Metrics records the duration of every request, labelled by the route
+// pattern it matched rather than its path to keep the number of series bounded.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		// Websocket connections last as long as the client stays, they would
+		// only skew the latencies
+		if recorder.status == http.StatusSwitchingProtocols {
+			return
+		}
+
+		route := r.Pattern
+		if len(route) == 0 {
+			route = "unmatched"
+		}
+		requestDurations.Observe(time.Since(start).Seconds(), r.Method, route, strconv.Itoa(recorder.status))
+	})
+}
+
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				log.Printf("[%s] panic serving %s %s: %v\n%s", GetRequestID(r), r.Method, r.URL.Path, err, debug.Stack())
+				writeError(w, errs.Internal(errors.New("panic while serving request")))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORS allows cross origin requests from the given origins, "*" allowing any.
+func CORS(origins ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if len(origin) != 0 && (slices.Contains(origins, "*") || slices.Contains(origins, origin)) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{"Content-Type", VosDosSessionToken, RequestIDHeader}, ", "))
+				w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if r.Method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) != 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit rejects request bodies larger than maxBytes.
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAuth only lets requests with a valid session through.
+func RequireAuth(next http.Handler) http.Handler {
+	return HandlerWithAuth(next.ServeHTTP)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// openAPISpec documents Routes and the websocket messages, it is maintained by
+// hand and checked against the code by the tests of the package.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// openAPISchemas maps the component schemas of the spec to the types they
+// describe.
+var openAPISchemas = map[string]any{
+	"ErrorResponse":        ErrorResponse{},
+	"User":                 game.User{},
+	"Player":               game.Player{},
+	"Clan":                 game.Clan{},
+	"ClanInvite":           game.ClanInvite{},
+	"ClanAuditEntry":       game.ClanAuditEntry{},
+	"WeaponClass":          game.WeaponClass{},
+	"Weapon":               game.Weapon{},
+	"ChatMessage":          game.ChatMessage{},
+	"ChatIgnore":           game.ChatIgnore{},
+	"Friend":               game.Friend{},
+	"FriendList":           game.FriendList{},
+	"Party":                game.Party{},
+	"PartyMember":          game.PartyMember{},
+	"PartyInvite":          game.PartyInvite{},
+	"Credentials":          Credentials{},
+	"ClanPayload":          ClanPayload{},
+	"ClanInvitePayload":    ClanInvitePayload{},
+	"ClanSettingsPayload":  ClanSettingsPayload{},
+	"ClanMemberPayload":    ClanMemberPayload{},
+	"ChatIgnorePayload":    ChatIgnorePayload{},
+	"FriendPayload":        FriendPayload{},
+	"Message":              ws.Message{},
+	"ChatRequest":          game.ChatRequest{},
+	"MovedEntity":          game.MovedEntity{},
+	"PlayerUpgrade":        game.PlayerUpgrade{},
+	"UpgradeChoice":        game.UpgradeChoice{},
+	"UpgradeResult":        game.UpgradeResult{},
+	"Enemy":                game.Enemy{},
+	"Projectile":           game.Projectile{},
+	"Pickup":               game.Pickup{},
+	"PickupCollect":        game.PickupCollect{},
+	"ExpShare":             game.ExpShare{},
+	"DownedPlayer":         game.DownedPlayer{},
+	"RunSummary":           game.RunSummary{},
+	"BossHealth":           game.BossHealth{},
+	"Telegraph":            game.Telegraph{},
+	"MapLayout":            game.MapLayout{},
+	"MapChunk":             game.MapChunk{},
+	"MapArea":              definitions.Area{},
+	"MapZone":              definitions.Zone{},
+	"WeaponDefinition":     definitions.Weapon{},
+	"EvolutionDefinition":  definitions.Evolution{},
+	"ProjectileDefinition": definitions.Projectile{},
+	"EnemyDefinition":      definitions.Enemy{},
+	"LootDefinition":       definitions.Loot{},
+	"BossDefinition":       definitions.Boss{},
+	"BossPhase":            definitions.BossPhase{},
+	"BossAttack":           definitions.BossAttack{},
+	"PassiveDefinition":    definitions.Passive{},
+	"DifficultyDefinition": definitions.Difficulty{},
+	"CurseDefinition":      definitions.Curse{},
+	"RunMode":              game.RunMode{},
+	"TuningDefinition":     definitions.Tuning{},
+	"PlayerTuning":         definitions.PlayerTuning{},
+	"SpawnerTuning":        definitions.SpawnerTuning{},
+	"ExpCurve":             definitions.ExpCurve{},
+	"PickupTuning":         definitions.PickupTuning{},
+	"ReviveTuning":         definitions.ReviveTuning{},
+	"UpgradeTuning":        definitions.UpgradeTuning{},
+	"MapTuning":            definitions.MapTuning{},
+	"PartyTuning":          definitions.PartyTuning{},
+	"ReloadResponse":       ReloadResponse{},
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+	WebSocket struct {
+		Client map[string]json.RawMessage `json:"client"`
+		Server map[string]json.RawMessage `json:"server"`
+	} `json:"x-websocket"`
+}
+
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
+
+// ValidateOpenAPI checks that the spec documents exactly the registered
+// routes, that its schemas have the json fields of the Go types and that it
+// lists every websocket message type.
+func ValidateOpenAPI() error {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+
+	var problems []string
+
+	documented := map[string]bool{}
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for _, route := range Routes {
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			problems = append(problems, "route not documented: "+key)
+		}
+		delete(documented, key)
+	}
+	for key := range documented {
+		problems = append(problems, "documented route does not exist: "+key)
+	}
+
+	for name, v := range openAPISchemas {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			problems = append(problems, "schema not documented: "+name)
+			continue
+		}
+
+		fields := jsonFields(reflect.TypeOf(v))
+		for _, field := range fields {
+			if _, ok := schema.Properties[field]; !ok {
+				problems = append(problems, fmt.Sprintf("schema %s is missing property %s", name, field))
+			}
+		}
+		for property := range schema.Properties {
+			if !slices.Contains(fields, property) {
+				problems = append(problems, fmt.Sprintf("schema %s has unknown property %s", name, property))
+			}
+		}
+	}
+
+	problems = append(problems, compareEvents("client", game.ClientEvents, doc.WebSocket.Client)...)
+	problems = append(problems, compareEvents("server", game.ServerEvents, doc.WebSocket.Server)...)
+
+	if len(problems) != 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi spec is out of date:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+
+	return nil
+}
+
+// jsonFields returns the names t's fields are encoded with by encoding/json,
+// including the fields of embedded structs.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func compareEvents(side string, events []string, documented map[string]json.RawMessage) []string {
+	var problems []string
+	for _, event := range events {
+		if _, ok := documented[event]; !ok {
+			problems = append(problems, fmt.Sprintf("%s event not documented: %s", side, event))
+		}
+	}
+	for event := range documented {
+		if !slices.Contains(events, event) {
+			problems = append(problems, fmt.Sprintf("documented %s event does not exist: %s", side, event))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,603 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/reload"
+)
+
+const testDataDir = "../../data"
+const testAdminToken = "test-admin-token"
+
+// testDatabaseURL names the database the operations needing one are tested
+// against, its tables are dropped and recreated by scripts/init-db.sql.
+const testDatabaseURL = "TEST_DATABASE_URL"
+
+func TestMain(m *testing.M) {
+	set, err := definitions.Load(testDataDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	definitions.SetCurrent(set)
+	reload.DataDir = testDataDir
+	AdminToken = testAdminToken
+
+	os.Exit(m.Run())
+}
+
+func TestOpenAPIMatchesCode(t *testing.T) {
+	if err := ValidateOpenAPI(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// apiStep is a request to a documented operation and the status it must
+// answer with, its body being checked against the spec.
+type apiStep struct {
+	op     string // documented method and path
+	user   string // user whose session token is sent, none if empty
+	admin  bool   // send the admin token
+	query  func() url.Values
+	body   func() any
+	status int
+	save   func(t *testing.T, body []byte) // reads what later steps need
+}
+
+// apiState is what the steps of a scenario share.
+type apiState struct {
+	tokens   map[string]string
+	users    map[string]uint
+	players  map[string]uint
+	clanID   uint
+	weaponID uint
+	invite   string
+}
+
+func newAPIState() *apiState {
+	return &apiState{tokens: map[string]string{}, users: map[string]uint{}, players: map[string]uint{}}
+}
+
+func query(pairs ...string) func() url.Values {
+	return func() url.Values {
+		values := url.Values{}
+		for i := 0; i < len(pairs); i += 2 {
+			values.Set(pairs[i], pairs[i+1])
+		}
+		return values
+	}
+}
+
+func body(v any) func() any {
+	return func() any { return v }
+}
+
+func decodeID(t *testing.T, body []byte) uint {
+	t.Helper()
+	var v struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v.ID
+}
+
+func id(v uint) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// publicSteps need neither the database nor a session.
+func publicSteps(spec *testSpec) []apiStep {
+	steps := []apiStep{
+		{op: "GET /openapi.json", status: http.StatusOK},
+		{op: "GET /definitions/weapons", status: http.StatusOK},
+		{op: "GET /definitions/evolutions", status: http.StatusOK},
+		{op: "GET /definitions/projectiles", status: http.StatusOK},
+		{op: "GET /definitions/enemies", status: http.StatusOK},
+		{op: "GET /definitions/bosses", status: http.StatusOK},
+		{op: "GET /definitions/passives", status: http.StatusOK},
+		{op: "GET /definitions/difficulties", status: http.StatusOK},
+		{op: "GET /definitions/curses", status: http.StatusOK},
+		{op: "GET /definitions/tuning", status: http.StatusOK},
+		{op: "GET /info/user", status: http.StatusBadRequest},
+		{op: "GET /info/player", query: query("player_id", "nope"), status: http.StatusBadRequest},
+		{op: "GET /info/weapon", status: http.StatusBadRequest},
+		{op: "GET /info/weapon_class", status: http.StatusBadRequest},
+	}
+
+	// Every secured operation rejects requests without credentials
+	for _, op := range spec.operations() {
+		if security, _ := spec.operation(op)["security"].([]any); len(security) != 0 {
+			steps = append(steps, apiStep{op: op, status: http.StatusUnauthorized})
+		}
+	}
+
+	return steps
+}
+
+// databaseSteps play a scenario going through every operation needing the
+// database.
+func databaseSteps(s *apiState) []apiStep {
+	var steps []apiStep
+	for _, name := range []string{"alice", "bob", "carol"} {
+		steps = append(steps,
+			apiStep{op: "POST /signup", body: body(Credentials{name, "password"}), status: http.StatusCreated},
+			apiStep{op: "POST /login", body: body(Credentials{name, "password"}), status: http.StatusCreated,
+				save: func(t *testing.T, body []byte) { s.tokens[name] = string(body) }},
+			apiStep{op: "GET /info/user", query: query("username", name), status: http.StatusOK,
+				save: func(t *testing.T, body []byte) { s.users[name] = decodeID(t, body) }},
+			apiStep{op: "GET /info/player", query: query("username", name), status: http.StatusOK,
+				save: func(t *testing.T, body []byte) { s.players[name] = decodeID(t, body) }},
+		)
+	}
+
+	steps = append(steps,
+		apiStep{op: "POST /signup", body: body(Credentials{"alice", "password"}), status: http.StatusConflict},
+		apiStep{op: "POST /login", body: body(Credentials{"alice", "wrong"}), status: http.StatusUnauthorized},
+		apiStep{op: "GET /verify_session", user: "alice", status: http.StatusOK},
+		apiStep{op: "GET /info/user", query: func() url.Values { return query("user_id", id(s.users["bob"]))() }, status: http.StatusOK},
+		apiStep{op: "GET /info/player", query: func() url.Values { return query("player_id", id(s.players["bob"]))() }, status: http.StatusOK},
+		apiStep{op: "GET /info/user", query: query("username", "nobody"), status: http.StatusNotFound},
+		apiStep{op: "GET /info/current_user", user: "alice", status: http.StatusOK},
+		apiStep{op: "GET /info/current_player", user: "alice", status: http.StatusOK},
+		apiStep{op: "GET /info/weapon_class", query: query("weapon_class_id", "1"), status: http.StatusOK},
+		apiStep{op: "GET /info/current_weapons", user: "alice", status: http.StatusOK,
+			save: func(t *testing.T, body []byte) {
+				// New players have no weapons, give alice one for the next steps
+				weapon, err := db.CreateWeapon(context.Background(), 1, 1, s.players["alice"])
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.weaponID = weapon.ID
+			}},
+		apiStep{op: "GET /info/weapon", query: func() url.Values { return query("weapon_id", id(s.weaponID))() }, status: http.StatusOK},
+		apiStep{op: "GET /info/current_weapons", user: "alice", status: http.StatusOK},
+
+		apiStep{op: "GET /info/current_clan", user: "alice", status: http.StatusNotFound},
+		apiStep{op: "POST /clan/create", user: "alice", body: body(ClanPayload{Name: "Squares"}), status: http.StatusBadRequest},
+		apiStep{op: "POST /clan/create", user: "alice", body: body(ClanPayload{Name: "Squares", Password: "secret"}), status: http.StatusCreated},
+		apiStep{op: "POST /clan/create", user: "bob", body: body(ClanPayload{Name: "Squares", Password: "secret"}), status: http.StatusConflict},
+		apiStep{op: "GET /info/clan", query: query("clan_name", "Squares"), status: http.StatusOK,
+			save: func(t *testing.T, body []byte) { s.clanID = decodeID(t, body) }},
+		apiStep{op: "GET /info/clan", query: func() url.Values { return query("clan_id", id(s.clanID))() }, status: http.StatusOK},
+		apiStep{op: "GET /info/current_clan", user: "alice", status: http.StatusOK},
+		apiStep{op: "POST /clan/join", user: "bob", body: body(ClanPayload{Name: "Squares", Password: "wrong"}), status: http.StatusForbidden},
+		apiStep{op: "POST /clan/join", user: "bob", body: body(ClanPayload{Name: "Squares", Password: "secret"}), status: http.StatusOK},
+		apiStep{op: "GET /clan/members", user: "bob", status: http.StatusOK},
+		apiStep{op: "GET /clan/members", query: func() url.Values { return query("clan_id", id(s.clanID))() }, user: "carol", status: http.StatusOK},
+		apiStep{op: "POST /clan/promote", user: "bob", body: func() any { return ClanMemberPayload{s.players["alice"]} }, status: http.StatusForbidden},
+		apiStep{op: "POST /clan/promote", user: "alice", body: func() any { return ClanMemberPayload{s.players["bob"]} }, status: http.StatusOK},
+		apiStep{op: "POST /clan/demote", user: "alice", body: func() any { return ClanMemberPayload{s.players["bob"]} }, status: http.StatusOK},
+		apiStep{op: "GET /clan/audit", user: "alice", query: query("limit", "10"), status: http.StatusOK},
+		apiStep{op: "POST /clan/settings", user: "alice", body: body(ClanSettingsPayload{JoinPolicy: game.ClanPolicyInviteOnly}), status: http.StatusOK},
+		apiStep{op: "POST /clan/invite/create", user: "alice", body: body(ClanInvitePayload{MaxUses: 1}), status: http.StatusCreated,
+			save: func(t *testing.T, body []byte) { s.invite = decodeInviteCode(t, body) }},
+		apiStep{op: "GET /clan/invites", user: "alice", status: http.StatusOK},
+		apiStep{op: "DELETE /clan/invite/revoke", user: "alice", query: func() url.Values { return query("invite", s.invite)() }, status: http.StatusOK},
+		apiStep{op: "POST /clan/invite/create", user: "alice", body: body(ClanInvitePayload{MaxUses: 1, ExpiresIn: 3600}), status: http.StatusCreated,
+			save: func(t *testing.T, body []byte) { s.invite = decodeInviteCode(t, body) }},
+		apiStep{op: "POST /clan/join", user: "carol", query: func() url.Values { return query("invite", s.invite)() }, status: http.StatusOK},
+		apiStep{op: "POST /clan/kick", user: "alice", body: func() any { return ClanMemberPayload{s.players["bob"]} }, status: http.StatusOK},
+		apiStep{op: "POST /clan/leave", user: "alice", status: http.StatusConflict},
+		apiStep{op: "POST /clan/transfer", user: "alice", body: func() any { return ClanMemberPayload{s.players["carol"]} }, status: http.StatusOK},
+		apiStep{op: "DELETE /clan/delete", user: "alice", status: http.StatusForbidden},
+		apiStep{op: "POST /clan/leave", user: "alice", status: http.StatusOK},
+		apiStep{op: "DELETE /clan/delete", user: "carol", status: http.StatusOK},
+
+		apiStep{op: "GET /chat/history", user: "alice", status: http.StatusOK},
+		apiStep{op: "GET /chat/history", user: "alice", query: query("channel", game.ChatChannelClan), status: http.StatusNotFound},
+		apiStep{op: "GET /chat/history", user: "alice", query: query("channel", game.ChatChannelDirect, "limit", "10"), status: http.StatusOK},
+		apiStep{op: "POST /chat/mute", user: "alice", body: body(ChatIgnorePayload{"bob"}), status: http.StatusOK},
+		apiStep{op: "POST /chat/block", user: "alice", body: body(ChatIgnorePayload{"carol"}), status: http.StatusOK},
+		apiStep{op: "GET /chat/ignores", user: "alice", status: http.StatusOK},
+		apiStep{op: "POST /chat/unmute", user: "alice", body: body(ChatIgnorePayload{"bob"}), status: http.StatusOK},
+		apiStep{op: "POST /chat/unblock", user: "alice", body: body(ChatIgnorePayload{"carol"}), status: http.StatusOK},
+		apiStep{op: "POST /chat/mute", user: "alice", body: body(ChatIgnorePayload{"alice"}), status: http.StatusBadRequest},
+
+		apiStep{op: "POST /friends/request", user: "alice", body: body(FriendPayload{"bob"}), status: http.StatusCreated},
+		apiStep{op: "GET /friends", user: "bob", status: http.StatusOK},
+		apiStep{op: "POST /friends/accept", user: "bob", body: body(FriendPayload{"alice"}), status: http.StatusOK},
+		apiStep{op: "GET /friends", user: "alice", status: http.StatusOK},
+		apiStep{op: "DELETE /friends/remove", user: "alice", query: query("username", "bob"), status: http.StatusOK},
+		apiStep{op: "POST /friends/request", user: "bob", body: body(FriendPayload{"alice"}), status: http.StatusCreated},
+		apiStep{op: "POST /friends/request", user: "alice", body: body(FriendPayload{"bob"}), status: http.StatusOK},
+		apiStep{op: "POST /friends/request", user: "alice", body: body(FriendPayload{"carol"}), status: http.StatusCreated},
+		apiStep{op: "POST /friends/decline", user: "carol", body: body(FriendPayload{"alice"}), status: http.StatusOK},
+
+		apiStep{op: "POST /admin/reload", status: http.StatusUnauthorized},
+		apiStep{op: "POST /admin/reload", admin: true, status: http.StatusOK},
+
+		apiStep{op: "POST /logout", user: "alice", status: http.StatusOK},
+		apiStep{op: "GET /verify_session", user: "alice", status: http.StatusUnauthorized},
+	)
+	return steps
+}
+
+func decodeInviteCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var invite game.ClanInvite
+	if err := json.Unmarshal(body, &invite); err != nil {
+		t.Fatal(err)
+	}
+	return invite.Code
+}
+
+func TestOpenAPIOperationsAreTested(t *testing.T) {
+	spec := loadTestSpec(t)
+
+	succeeded := map[string]bool{}
+	for _, step := range append(publicSteps(spec), databaseSteps(newAPIState())...) {
+		if spec.operation(step.op) == nil {
+			t.Errorf("step of undocumented operation %s", step.op)
+		}
+		if step.status < 300 {
+			succeeded[step.op] = true
+		}
+	}
+
+	for _, op := range spec.operations() {
+		if !succeeded[op] {
+			t.Errorf("no successful request to %s is tested", op)
+		}
+	}
+}
+
+func TestOpenAPIPublicOperations(t *testing.T) {
+	spec := loadTestSpec(t)
+	runSteps(t, spec, newAPIState(), publicSteps(spec))
+}
+
+func TestOpenAPIDatabaseOperations(t *testing.T) {
+	databaseURL := os.Getenv(testDatabaseURL)
+	if databaseURL == "" {
+		t.Skip(testDatabaseURL + " is not set")
+	}
+
+	spec := loadTestSpec(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	if err := db.Connect(ctx, databaseURL); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.CloseDB)
+
+	schema, err := os.ReadFile("../../scripts/init-db.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetDB().Exec(ctx, string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SyncWeaponClasses(ctx, definitions.Current().Weapons); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloading the definitions waits for the world loop to apply them
+	go game.StartWorld(ctx)
+
+	s := newAPIState()
+	runSteps(t, spec, s, databaseSteps(s))
+}
+
+// runSteps sends the requests of the steps in order through the router,
+// stopping at the first step answering with an unexpected status as the
+// following ones depend on it.
+func runSteps(t *testing.T, spec *testSpec, s *apiState, steps []apiStep) {
+	router := NewRouter()
+
+	for i, step := range steps {
+		name := fmt.Sprintf("%d %s", i, step.op)
+		op := spec.operation(step.op)
+		if op == nil {
+			t.Fatalf("%s: operation is not documented", name)
+		}
+
+		method, path, _ := strings.Cut(step.op, " ")
+		target := APIPrefix + path
+
+		if step.query != nil {
+			values := step.query()
+			for key := range values {
+				if !spec.hasParameter(op, key) {
+					t.Errorf("%s: query parameter %s is not documented", name, key)
+				}
+			}
+			target += "?" + values.Encode()
+		}
+
+		var requestBody []byte
+		if step.body != nil {
+			var err error
+			if requestBody, err = json.Marshal(step.body()); err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range spec.checkRequestBody(op, requestBody) {
+				t.Errorf("%s: request %s", name, problem)
+			}
+		}
+
+		request := httptest.NewRequest(method, target, bytes.NewReader(requestBody))
+		if step.user != "" {
+			request.Header.Set(VosDosSessionToken, s.tokens[step.user])
+		}
+		if step.admin {
+			request.Header.Set("Authorization", "Bearer "+testAdminToken)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		for _, problem := range spec.checkResponse(op, recorder) {
+			t.Errorf("%s: response %s", name, problem)
+		}
+
+		if recorder.Code != step.status {
+			t.Fatalf("%s: status %d, want %d: %s", name, recorder.Code, step.status, recorder.Body)
+		}
+
+		if step.save != nil {
+			step.save(t, recorder.Body.Bytes())
+		}
+	}
+}
+
+// testSpec checks requests and responses against the schemas of the spec,
+// supporting the keywords it uses.
+type testSpec struct {
+	doc map[string]any
+}
+
+func loadTestSpec(t *testing.T) *testSpec {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(openAPISpec))
+	decoder.UseNumber()
+
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return &testSpec{doc}
+}
+
+// operations returns the documented operations as their method and path.
+func (s *testSpec) operations() []string {
+	var ops []string
+	for path, methods := range s.doc["paths"].(map[string]any) {
+		for method := range methods.(map[string]any) {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(ops)
+	return ops
+}
+
+// operation returns the documented operation, nil if there is none.
+func (s *testSpec) operation(op string) map[string]any {
+	method, path, _ := strings.Cut(op, " ")
+	methods, _ := s.doc["paths"].(map[string]any)[path].(map[string]any)
+	operation, _ := methods[strings.ToLower(method)].(map[string]any)
+	return operation
+}
+
+func (s *testSpec) hasParameter(op map[string]any, name string) bool {
+	parameters, _ := op["parameters"].([]any)
+	return slices.ContainsFunc(parameters, func(p any) bool {
+		return p.(map[string]any)["name"] == name
+	})
+}
+
+// resolve follows the $ref of the node, if any.
+func (s *testSpec) resolve(node map[string]any) map[string]any {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+
+		var current any = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			current = current.(map[string]any)[part]
+		}
+		node = current.(map[string]any)
+	}
+}
+
+func (s *testSpec) checkRequestBody(op map[string]any, body []byte) []string {
+	requestBody, ok := op["requestBody"].(map[string]any)
+	if !ok {
+		return []string{"body is not documented"}
+	}
+	content := s.resolve(requestBody)["content"].(map[string]any)
+	media, ok := content["application/json"].(map[string]any)
+	if !ok {
+		return []string{"json body is not documented"}
+	}
+	return s.checkJSON(media["schema"].(map[string]any), body)
+}
+
+func (s *testSpec) checkResponse(op map[string]any, recorder *httptest.ResponseRecorder) []string {
+	responses := op["responses"].(map[string]any)
+	response, ok := responses[strconv.Itoa(recorder.Code)].(map[string]any)
+	if !ok {
+		if response, ok = responses["default"].(map[string]any); !ok {
+			return []string{fmt.Sprintf("status %d is not documented", recorder.Code)}
+		}
+	}
+
+	content, ok := s.resolve(response)["content"].(map[string]any)
+	if !ok {
+		if recorder.Body.Len() != 0 {
+			return []string{fmt.Sprintf("status %d has no documented body but got %q", recorder.Code, recorder.Body)}
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if err != nil {
+		return []string{"invalid content type: " + err.Error()}
+	}
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("content type %s of status %d is not documented", mediaType, recorder.Code)}
+	}
+
+	switch mediaType {
+	case "application/json":
+		return s.checkJSON(media["schema"].(map[string]any), recorder.Body.Bytes())
+	case "text/plain":
+		if recorder.Body.Len() == 0 {
+			return []string{"text body is empty"}
+		}
+	}
+	return nil
+}
+
+func (s *testSpec) checkJSON(schema map[string]any, data []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"invalid json: " + err.Error()}
+	}
+	return s.check("body", schema, value)
+}
+
+// check returns the problems of value against the schema, located by path.
+func (s *testSpec) check(path string, schema map[string]any, value any) []string {
+	schema = s.resolve(schema)
+	if value == nil {
+		return []string{path + " is null"}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return []string{fmt.Sprintf("%s is %v, not one of %v", path, value, enum)}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, not an object", path, value)}
+		}
+
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing required property %s", path, name))
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		for name, v := range object {
+			if property, ok := properties[name].(map[string]any); ok {
+				problems = append(problems, s.check(path+"."+name, property, v)...)
+				continue
+			}
+
+			switch additional := schema["additionalProperties"].(type) {
+			case map[string]any:
+				problems = append(problems, s.check(path+"."+name, additional, v)...)
+			case nil:
+				// Objects documented without properties may hold anything
+				if properties != nil {
+					problems = append(problems, fmt.Sprintf("%s has undocumented property %s", path, name))
+				}
+			}
+		}
+
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, not an array", path, value)}
+		}
+
+		if minItems, ok := schema["minItems"].(json.Number); ok && int64(len(array)) < mustInt(minItems) {
+			problems = append(problems, fmt.Sprintf("%s has %d items, less than %s", path, len(array), minItems))
+		}
+		if maxItems, ok := schema["maxItems"].(json.Number); ok && int64(len(array)) > mustInt(maxItems) {
+			problems = append(problems, fmt.Sprintf("%s has %d items, more than %s", path, len(array), maxItems))
+		}
+
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			problems = append(problems, s.check(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, not a string", path, value)}
+		}
+
+		if maxLength, ok := schema["maxLength"].(json.Number); ok && int64(utf8.RuneCountInString(str)) > mustInt(maxLength) {
+			problems = append(problems, fmt.Sprintf("%s is longer than %s", path, maxLength))
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s is not a date-time: %v", path, err))
+			}
+		}
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, not a number", path, value)}
+		}
+
+		if schema["type"] == "integer" && strings.ContainsAny(number.String(), ".eE") {
+			problems = append(problems, fmt.Sprintf("%s is %s, not an integer", path, number))
+		}
+
+		f, _ := number.Float64()
+		if minimum, ok := schema["minimum"].(json.Number); ok && f < mustFloat(minimum) {
+			problems = append(problems, fmt.Sprintf("%s is %s, less than %s", path, number, minimum))
+		}
+		if maximum, ok := schema["maximum"].(json.Number); ok && f > mustFloat(maximum) {
+			problems = append(problems, fmt.Sprintf("%s is %s, more than %s", path, number, maximum))
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s is %T, not a boolean", path, value)}
+		}
+	}
+
+	return problems
+}
+
+func mustInt(n json.Number) int64 {
+	i, err := n.Int64()
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+func mustFloat(n json.Number) float64 {
+	f, err := n.Float64()
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
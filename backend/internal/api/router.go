@@ -0,0 +1,152 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// APIPrefix is the prefix of the current version of the REST API, routes are
+// also served under LegacyAPIPrefix for older clients.
+var APIPrefix = "/api/v1"
+var LegacyAPIPrefix = "/api"
+
+var MaxBodyBytes int64 = 1 << 20
+var AllowedOrigins = []string{"*"}
+
+type Route struct {
+	Method  string
+	Path    string // relative to the API prefix
+	Handler http.HandlerFunc
+	Auth    bool
+}
+
+var Routes = []Route{
+	{http.MethodGet, "/openapi.json", HandleOpenAPI, false},
+
+	{http.MethodGet, "/verify_session", HandleVerifySession, true},
+
+	{http.MethodPost, "/signup", HandleSignup, false},
+	{http.MethodPost, "/login", HandleLogin, false},
+	{http.MethodPost, "/logout", HandleLogout, false},
+
+	{http.MethodGet, "/info/user", HandleGetUserInfo, false},
+	{http.MethodGet, "/info/player", HandleGetPlayerInfo, false},
+	{http.MethodGet, "/info/clan", HandleGetClanInfo, false},
+	{http.MethodGet, "/info/weapon_class", HandleGetWeaponClassInfo, false},
+	{http.MethodGet, "/info/weapon", HandleGetWeaponInfo, false},
+
+	{http.MethodGet, "/definitions/weapons", HandleGetWeaponDefinitions, false},
+	{http.MethodGet, "/definitions/evolutions", HandleGetEvolutionDefinitions, false},
+	{http.MethodGet, "/definitions/projectiles", HandleGetProjectileDefinitions, false},
+	{http.MethodGet, "/definitions/enemies", HandleGetEnemyDefinitions, false},
+	{http.MethodGet, "/definitions/bosses", HandleGetBossDefinitions, false},
+	{http.MethodGet, "/definitions/passives", HandleGetPassiveDefinitions, false},
+	{http.MethodGet, "/definitions/difficulties", HandleGetDifficultyDefinitions, false},
+	{http.MethodGet, "/definitions/curses", HandleGetCurseDefinitions, false},
+	{http.MethodGet, "/definitions/tuning", HandleGetTuningDefinitions, false},
+
+	{http.MethodPost, "/admin/reload", HandleReloadDefinitions, false},
+
+	{http.MethodGet, "/info/current_user", HandleGetCurrentUserInfo, true},
+	{http.MethodGet, "/info/current_player", HandleGetCurrentPlayerInfo, true},
+	{http.MethodGet, "/info/current_clan", HandleGetCurrentClanInfo, true},
+	{http.MethodGet, "/info/current_weapons", HandleGetCurrentWeaponsInfo, true},
+
+	{http.MethodPost, "/clan/create", HandleCreateClan, true},
+	{http.MethodDelete, "/clan/delete", HandleDeleteClan, true},
+	{http.MethodPost, "/clan/join", HandleJoinClan, true},
+	{http.MethodPost, "/clan/leave", HandleLeaveClan, true},
+	{http.MethodGet, "/clan/members", HandleGetClanMembers, true},
+	{http.MethodPost, "/clan/kick", HandleKickClanMember, true},
+	{http.MethodPost, "/clan/promote", HandlePromoteClanMember, true},
+	{http.MethodPost, "/clan/demote", HandleDemoteClanMember, true},
+	{http.MethodPost, "/clan/transfer", HandleTransferClanOwnership, true},
+	{http.MethodGet, "/clan/audit", HandleGetClanAuditLog, true},
+	{http.MethodPost, "/clan/settings", HandleUpdateClanSettings, true},
+	{http.MethodGet, "/clan/invites", HandleGetClanInvites, true},
+	{http.MethodPost, "/clan/invite/create", HandleCreateClanInvite, true},
+	{http.MethodDelete, "/clan/invite/revoke", HandleRevokeClanInvite, true},
+
+	{http.MethodGet, "/chat/history", HandleGetChatHistory, true},
+	{http.MethodGet, "/chat/ignores", HandleGetChatIgnores, true},
+	{http.MethodPost, "/chat/mute", HandleMuteUser, true},
+	{http.MethodPost, "/chat/unmute", HandleUnmuteUser, true},
+	{http.MethodPost, "/chat/block", HandleBlockUser, true},
+	{http.MethodPost, "/chat/unblock", HandleUnblockUser, true},
+
+	{http.MethodGet, "/friends", HandleGetFriends, true},
+	{http.MethodPost, "/friends/request", HandleSendFriendRequest, true},
+	{http.MethodPost, "/friends/accept", HandleAcceptFriendRequest, true},
+	{http.MethodPost, "/friends/decline", HandleDeclineFriendRequest, true},
+	{http.MethodDelete, "/friends/remove", HandleRemoveFriend, true},
+}
+
+// NewRouter registers the API routes under the versioned and legacy prefixes,
+// the websocket endpoint and the frontend, wrapped in the middleware chain.
+func NewRouter() http.Handler {
+	mux := http.NewServeMux()
+
+	for _, route := range Routes {
+		var handler http.Handler = route.Handler
+		if route.Auth {
+			handler = RequireAuth(handler)
+		}
+
+		mux.Handle(route.Method+" "+APIPrefix+route.Path, handler)
+		mux.Handle(route.Method+" "+LegacyAPIPrefix+route.Path, handler)
+	}
+
+	mux.HandleFunc("GET /healthz", HandleHealthz)
+	mux.HandleFunc("GET /readyz", HandleReadyz)
+	mux.HandleFunc("GET /metrics", metrics.Handler)
+	mux.HandleFunc("GET /ws", ws.HandleWebSocket)
+	mux.HandleFunc("/", handleUnrouted)
+
+	// RequestID comes first so the panics Recovery logs carry the ID
+	return Chain(mux,
+		RequestID,
+		Recovery,
+		Logging,
+		Metrics,
+		CORS(AllowedOrigins...),
+		BodyLimit(MaxBodyBytes),
+	)
+}
+
+// handleUnrouted serves the frontend to the requests no route matches. API
+// requests get a JSON error instead, 405 if their path is routed for other
+// methods and 404 otherwise.
+func handleUnrouted(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, APIPrefix+"/")
+	if !ok {
+		path, ok = strings.CutPrefix(r.URL.Path, LegacyAPIPrefix+"/")
+	}
+	if !ok {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, errs.MethodNotAllowed())
+			return
+		}
+		HandleFrontend(w, r)
+		return
+	}
+
+	path = "/" + path
+	var allowed []string
+	for _, route := range Routes {
+		if route.Path == path {
+			allowed = append(allowed, route.Method)
+		}
+	}
+
+	if len(allowed) == 0 {
+		writeError(w, errs.NotFound("no such API route"))
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, errs.MethodNotAllowed())
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+)
+
+func TestRouterUnrouted(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		code   string
+		allow  string
+	}{
+		{"unknown path", http.MethodGet, "/api/v1/nope", http.StatusNotFound, errs.KindNotFound, ""},
+		{"unknown legacy path", http.MethodGet, "/api/nope", http.StatusNotFound, errs.KindNotFound, ""},
+		{"unknown nested path", http.MethodPost, "/api/v1/clan/nope", http.StatusNotFound, errs.KindNotFound, ""},
+		{"wrong method", http.MethodGet, "/api/v1/clan/create", http.StatusMethodNotAllowed, errs.KindMethodNotAllowed, http.MethodPost},
+		{"wrong legacy method", http.MethodPost, "/api/friends/remove", http.StatusMethodNotAllowed, errs.KindMethodNotAllowed, http.MethodDelete},
+		{"wrong method of get route", http.MethodDelete, "/api/v1/openapi.json", http.StatusMethodNotAllowed, errs.KindMethodNotAllowed, http.MethodGet},
+		{"wrong method of frontend", http.MethodPost, "/index.html", http.StatusMethodNotAllowed, errs.KindMethodNotAllowed, "GET, HEAD"},
+	}
+
+	router := NewRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status %d, want %d", recorder.Code, tt.status)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("content type %q, want application/json", got)
+			}
+			if got := recorder.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("allow %q, want %q", got, tt.allow)
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Code != tt.code {
+				t.Errorf("code %q, want %q", response.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestRouterServesRoutes(t *testing.T) {
+	router := NewRouter()
+	for _, target := range []string{"/api/v1/openapi.json", "/api/openapi.json"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200", target, recorder.Code)
+		}
+	}
+}
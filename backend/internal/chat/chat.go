@@ -0,0 +1,187 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/session"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+const MaxMessageLength = 256
+const DefaultHistoryLimit = 50
+const MaxHistoryLimit = 200
+
+// Each user may send rateLimitCount messages per rateLimitWindow.
+var rateLimitCount = 5
+var rateLimitWindow = 10 * time.Second
+
+var rateLimitMu sync.Mutex
+var sentAt = make(map[uint][]time.Time)
+var prunedAt time.Time
+
+// pruneSentAt forgets the users whose messages all left the window, at most
+// once per window. The caller must hold rateLimitMu.
+func pruneSentAt(now time.Time) {
+	if now.Sub(prunedAt) < rateLimitWindow {
+		return
+	}
+	prunedAt = now
+
+	for userID, times := range sentAt {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= rateLimitWindow {
+			delete(sentAt, userID)
+		}
+	}
+}
+
+func allowMessage(userID uint) bool {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	now := time.Now()
+	pruneSentAt(now)
+	recent := sentAt[userID][:0]
+	for _, t := range sentAt[userID] {
+		if now.Sub(t) < rateLimitWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= rateLimitCount {
+		sentAt[userID] = recent
+		return false
+	}
+
+	sentAt[userID] = append(recent, now)
+	return true
+}
+
+func sendError(sessionID string, message string) {
+	ws.GetHub().SendDirect(sessionID, ws.Message{Type: game.ServerChatError, Data: message})
+}
+
+// HandleClientChat validates, persists and delivers a ClientChatSend message.
+func HandleClientChat(client *ws.Client, message ws.Message) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	username, ok := session.GetUsername(client.SessionID)
+	if !ok {
+		sendError(client.SessionID, "invalid session token")
+		return
+	}
+
+	var request game.ChatRequest
+	if data, err := json.Marshal(message.Data); err != nil {
+		sendError(client.SessionID, "invalid chat message")
+		return
+	} else if err := json.Unmarshal(data, &request); err != nil {
+		sendError(client.SessionID, "invalid chat message")
+		return
+	}
+
+	request.Text = strings.TrimSpace(request.Text)
+	if len(request.Text) == 0 {
+		sendError(client.SessionID, "message is empty")
+		return
+	}
+
+	if !utf8.ValidString(request.Text) || utf8.RuneCountInString(request.Text) > MaxMessageLength {
+		sendError(client.SessionID, "message is too long")
+		return
+	}
+
+	player, err := db.GetPlayerByUsername(c, username)
+	if err != nil {
+		log.Println("Error loading chat sender:", err)
+		sendError(client.SessionID, "failed to send message")
+		return
+	}
+
+	if !allowMessage(player.UserID) {
+		sendError(client.SessionID, "sending messages too fast")
+		return
+	}
+
+	// recipients maps the usernames who may receive the message, nil means everyone
+	var recipients map[string]bool
+	var clanID, recipientID *uint
+	var recipientName string
+
+	switch request.Channel {
+	case game.ChatChannelGlobal:
+	case game.ChatChannelClan:
+		if player.ClanID == nil {
+			sendError(client.SessionID, "user is not in a clan")
+			return
+		}
+		clanID = player.ClanID
+
+		names, err := db.GetClanMemberNames(c, *clanID)
+		if err != nil {
+			log.Println("Error loading clan members:", err)
+			sendError(client.SessionID, "failed to send message")
+			return
+		}
+
+		recipients = make(map[string]bool, len(names))
+		for _, name := range names {
+			recipients[name] = true
+		}
+	case game.ChatChannelDirect:
+		recipient, err := db.GetUserByName(c, request.To)
+		if err != nil {
+			sendError(client.SessionID, "user not found")
+			return
+		}
+		recipientID = &recipient.ID
+		recipientName = recipient.Username
+
+		recipients = map[string]bool{username: true, recipient.Username: true}
+	default:
+		sendError(client.SessionID, "invalid chat channel")
+		return
+	}
+
+	ignoredBy, err := db.GetChatIgnoredBy(c, player.UserID)
+	if err != nil {
+		log.Println("Error loading chat ignores:", err)
+		sendError(client.SessionID, "failed to send message")
+		return
+	}
+
+	if request.Channel == game.ChatChannelDirect && ignoredBy[recipientName] == game.ChatIgnoreBlock {
+		sendError(client.SessionID, "user does not accept messages from you")
+		return
+	}
+
+	chatMessage, err := db.CreateChatMessage(c, request.Channel, player.UserID, clanID, recipientID, request.Text)
+	if err != nil {
+		log.Println("Error saving chat message:", err)
+		sendError(client.SessionID, "failed to send message")
+		return
+	}
+	chatMessage.Sender = username
+
+	hub := ws.GetHub()
+	for _, sessionID := range hub.SessionIDs() {
+		name, ok := session.GetUsername(sessionID)
+		if !ok || (recipients != nil && !recipients[name]) {
+			continue
+		}
+
+		// Muting only hides channel messages, blocking hides everything
+		if kind, ignored := ignoredBy[name]; ignored && (kind == game.ChatIgnoreBlock || request.Channel != game.ChatChannelDirect) {
+			continue
+		}
+
+		hub.SendDirect(sessionID, ws.Message{Type: game.ServerChatMessage, Data: chatMessage})
+	}
+}
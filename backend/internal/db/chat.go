@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"valley-of-survival-dawn-of-squares/internal/game"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func CreateChatMessage(
+	c context.Context,
+	channel string,
+	senderID uint,
+	clanID *uint,
+	recipientID *uint,
+	text string,
+) (_ *game.ChatMessage, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	m := game.ChatMessage{
+		Channel:     channel,
+		SenderID:    senderID,
+		ClanID:      clanID,
+		RecipientID: recipientID,
+		Text:        text,
+	}
+	err = tx.QueryRow(c, `
+		INSERT INTO chat_messages (channel, sender_id, clan_id, recipient_id, text)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, channel, senderID, clanID, recipientID, text).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// GetChatHistory returns up to limit of the most recent messages of a channel
+// visible to the user, oldest first. Messages of muted senders are left out,
+// as are direct messages of blocked senders.
+func GetChatHistory(c context.Context, userID uint, channel string, clanID *uint, limit uint) (_ []*game.ChatMessage, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT m.id, m.channel, m.sender_id, u.name, m.clan_id, m.recipient_id, m.text, m.created_at
+		FROM chat_messages m
+		JOIN users u ON u.id = m.sender_id
+		WHERE m.channel = $2
+			AND ($3::INTEGER IS NULL OR m.clan_id = $3)
+			AND (m.channel <> $5 OR m.sender_id = $1 OR m.recipient_id = $1)
+			AND NOT EXISTS (
+				SELECT 1 FROM chat_ignores i
+				WHERE i.user_id = $1 AND i.target_id = m.sender_id
+					AND (i.kind = $6 OR m.channel <> $5)
+			)
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $4;
+	`, userID, channel, clanID, limit, game.ChatChannelDirect, game.ChatIgnoreBlock)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*game.ChatMessage{}
+	for rows.Next() {
+		var m game.ChatMessage
+		if err := rows.Scan(&m.ID, &m.Channel, &m.SenderID, &m.Sender, &m.ClanID, &m.RecipientID, &m.Text, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func SetChatIgnore(c context.Context, userID uint, targetID uint, kind string) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err = tx.Exec(c, `
+		INSERT INTO chat_ignores (user_id, target_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, userID, targetID, kind); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+func RemoveChatIgnore(c context.Context, userID uint, targetID uint, kind string) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err = tx.Exec(c, `
+		DELETE FROM chat_ignores
+		WHERE user_id = $1 AND target_id = $2 AND kind = $3
+	`, userID, targetID, kind); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// GetChatIgnores returns the users muted or blocked by the user.
+func GetChatIgnores(c context.Context, userID uint) (_ []*game.ChatIgnore, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT u.name, i.kind
+		FROM chat_ignores i
+		JOIN users u ON u.id = i.target_id
+		WHERE i.user_id = $1
+		ORDER BY i.created_at;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ignores := []*game.ChatIgnore{}
+	for rows.Next() {
+		var ignore game.ChatIgnore
+		if err := rows.Scan(&ignore.Username, &ignore.Kind); err != nil {
+			return nil, err
+		}
+		ignores = append(ignores, &ignore)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return ignores, nil
+}
+
+// GetChatIgnoredBy returns the names of the users who muted or blocked the
+// target, mapped to the strongest kind of ignore.
+func GetChatIgnoredBy(c context.Context, targetID uint) (_ map[string]string, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT u.name, i.kind
+		FROM chat_ignores i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.target_id = $1;
+	`, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ignoredBy := make(map[string]string)
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			return nil, err
+		}
+		if ignoredBy[name] != game.ChatIgnoreBlock {
+			ignoredBy[name] = kind
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return ignoredBy, nil
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"valley-of-survival-dawn-of-squares/internal/game"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func insertClanAuditEntry(c context.Context, tx pgx.Tx, clanID uint, actorID uint, targetID *uint, action string) error {
+	_, err := tx.Exec(c, `
+		INSERT INTO clan_audit_log (clan_id, actor_id, target_id, action)
+		VALUES ($1, $2, $3, $4)
+	`, clanID, actorID, targetID, action)
+	return err
+}
+
+func GetClanAuditLog(c context.Context, clanID uint, limit uint) (_ []*game.ClanAuditEntry, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT id, clan_id, actor_id, target_id, action, created_at
+		FROM clan_audit_log
+		WHERE clan_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2;
+	`, clanID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*game.ClanAuditEntry{}
+	for rows.Next() {
+		var entry game.ClanAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.ClanID, &entry.ActorID, &entry.TargetID, &entry.Action, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SetClanRole changes the role of a player of the clan and records the action
+// in the clan's audit log.
+func SetClanRole(c context.Context, clanID uint, actorID uint, playerID uint, role string, action string) (err error) {
+	defer wrapError(&err, "player is not a member of the clan")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var targetID uint
+	err = tx.QueryRow(c, `
+		UPDATE players
+		SET clan_role = $3
+		WHERE id = $2 AND clan_id = $1
+		RETURNING user_id
+	`, clanID, playerID, role).Scan(&targetID)
+	if err != nil {
+		return err
+	}
+
+	if err = insertClanAuditEntry(c, tx, clanID, actorID, &targetID, action); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+func KickClanMember(c context.Context, clanID uint, actorID uint, playerID uint) (err error) {
+	defer wrapError(&err, "player is not a member of the clan")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var targetID uint
+	err = tx.QueryRow(c, `
+		UPDATE players
+		SET clan_id = NULL, clan_role = NULL
+		WHERE id = $2 AND clan_id = $1
+		RETURNING user_id
+	`, clanID, playerID).Scan(&targetID)
+	if err != nil {
+		return err
+	}
+
+	if err = insertClanAuditEntry(c, tx, clanID, actorID, &targetID, game.ClanActionKick); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// TransferClanOwnership makes the given player the owner of the clan, the
+// previous owner stays in the clan as an officer.
+func TransferClanOwnership(c context.Context, clanID uint, actorID uint, playerID uint) (err error) {
+	defer wrapError(&err, "player is not a member of the clan")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var targetID uint
+	err = tx.QueryRow(c, `
+		UPDATE players
+		SET clan_role = $3
+		WHERE id = $2 AND clan_id = $1
+		RETURNING user_id
+	`, clanID, playerID, game.ClanRoleOwner).Scan(&targetID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(c, `
+		UPDATE players
+		SET clan_role = $3
+		WHERE user_id = $2 AND clan_id = $1
+	`, clanID, actorID, game.ClanRoleOfficer); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(c, `
+		UPDATE clans
+		SET owner_id = $2
+		WHERE id = $1
+	`, clanID, targetID); err != nil {
+		return err
+	}
+
+	if err = insertClanAuditEntry(c, tx, clanID, actorID, &targetID, game.ClanActionTransfer); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+func GetClanMemberNames(c context.Context, clanID uint) (_ []string, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT u.name
+		FROM players p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.clan_id = $1;
+	`, clanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
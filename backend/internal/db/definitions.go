@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// SyncWeaponClasses writes the defined weapon classes to the weapon_classes
+// table, classes no longer defined are left in place for existing weapons.
+func SyncWeaponClasses(c context.Context, weapons []*definitions.Weapon) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, weapon := range weapons {
+		if _, err = tx.Exec(c, `
+			INSERT INTO weapon_classes (id, name, base_damage, base_range, base_rate_of_fire)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name,
+				base_damage = EXCLUDED.base_damage,
+				base_range = EXCLUDED.base_range,
+				base_rate_of_fire = EXCLUDED.base_rate_of_fire
+		`, weapon.ID, weapon.Name, weapon.DamageAt(1), weapon.Range, weapon.Cooldown); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(c, `
+		SELECT setval('weapon_classes_id_seq', (SELECT MAX(id) FROM weapon_classes))
+	`); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
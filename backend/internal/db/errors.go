@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes mapped to domain errors
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// wrapError converts the error pointed to by err into a domain error. Missing
+// rows become a not found error with the given message, or an internal error
+// if notFound is empty. It is meant to be deferred with a named error result.
+func wrapError(err *error, notFound string) {
+	if *err == nil {
+		return
+	}
+
+	var domainErr *errs.Error
+	if errors.As(*err, &domainErr) {
+		return
+	}
+
+	if errors.Is(*err, pgx.ErrNoRows) && len(notFound) != 0 {
+		*err = &errs.Error{Kind: errs.KindNotFound, Message: notFound, Err: *err}
+		return
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(*err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			*err = &errs.Error{Kind: errs.KindConflict, Message: "already exists", Err: *err,
+				Details: map[string]any{"constraint": pgErr.ConstraintName}}
+			return
+		case pgForeignKeyViolation, pgCheckViolation:
+			*err = &errs.Error{Kind: errs.KindValidation, Message: "invalid reference or value", Err: *err,
+				Details: map[string]any{"constraint": pgErr.ConstraintName}}
+			return
+		}
+	}
+
+	*err = errs.Internal(*err)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWrapError(t *testing.T) {
+	notFound := errs.NotFound("clan not found")
+
+	tests := []struct {
+		name       string
+		err        error
+		notFound   string
+		kind       string
+		constraint string
+	}{
+		{name: "unique violation", err: &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_name_key"},
+			kind: errs.KindConflict, constraint: "users_name_key"},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: pgForeignKeyViolation, ConstraintName: "players_clan_id_fkey"},
+			kind: errs.KindValidation, constraint: "players_clan_id_fkey"},
+		{name: "check violation", err: &pgconn.PgError{Code: pgCheckViolation, ConstraintName: "players_level_check"},
+			kind: errs.KindValidation, constraint: "players_level_check"},
+		{name: "wrapped violation", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgUniqueViolation}),
+			kind: errs.KindConflict},
+		{name: "other postgres error", err: &pgconn.PgError{Code: "40001"}, kind: errs.KindInternal},
+		{name: "missing row", err: pgx.ErrNoRows, notFound: "clan not found", kind: errs.KindNotFound},
+		{name: "missing row without message", err: pgx.ErrNoRows, kind: errs.KindInternal},
+		{name: "domain error", err: notFound, kind: errs.KindNotFound},
+		{name: "plain error", err: errors.New("connection reset"), kind: errs.KindInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.err
+			wrapError(&err, test.notFound)
+
+			e := errs.As(err)
+			if e.Kind != test.kind {
+				t.Fatalf("kind = %q, want %q", e.Kind, test.kind)
+			}
+			if len(test.constraint) != 0 && e.Details["constraint"] != test.constraint {
+				t.Errorf("constraint = %v, want %q", e.Details["constraint"], test.constraint)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("wrapped error lost its cause %v", test.err)
+			}
+		})
+	}
+}
+
+func TestWrapErrorNil(t *testing.T) {
+	var err error
+	wrapError(&err, "not found")
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	friendshipPending  = "pending"
+	friendshipAccepted = "accepted"
+)
+
+var (
+	ErrAlreadyFriends        = errs.Conflict("users are already friends")
+	ErrFriendRequestExists   = errs.Conflict("friend request already sent")
+	ErrFriendRequestNotFound = errs.NotFound("friend request not found")
+	ErrFriendshipNotFound    = errs.NotFound("users are not friends")
+)
+
+// CreateFriendRequest sends a friend request from the user to the friend. If
+// the friend already sent a request to the user it is accepted instead, which
+// is reported by the returned bool.
+func CreateFriendRequest(c context.Context, userID uint, friendID uint) (_ bool, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(c)
+
+	var requesterID uint
+	var status string
+	err = tx.QueryRow(c, `
+		SELECT user_id, status
+		FROM friendships
+		WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
+	`, userID, friendID).Scan(&requesterID, &status)
+
+	switch {
+	case err == pgx.ErrNoRows:
+		if _, err = tx.Exec(c, `
+			INSERT INTO friendships (user_id, friend_id, status)
+			VALUES ($1, $2, $3)
+		`, userID, friendID, friendshipPending); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(c)
+	case err != nil:
+		return false, err
+	case status == friendshipAccepted:
+		return false, ErrAlreadyFriends
+	case requesterID == userID:
+		return false, ErrFriendRequestExists
+	}
+
+	if _, err = tx.Exec(c, `
+		UPDATE friendships
+		SET status = $3
+		WHERE user_id = $1 AND friend_id = $2
+	`, friendID, userID, friendshipAccepted); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(c)
+}
+
+func AcceptFriendRequest(c context.Context, userID uint, requesterID uint) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, `
+		UPDATE friendships
+		SET status = $3
+		WHERE user_id = $1 AND friend_id = $2 AND status = $4
+	`, requesterID, userID, friendshipAccepted, friendshipPending)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFriendRequestNotFound
+	}
+
+	return tx.Commit(c)
+}
+
+func DeclineFriendRequest(c context.Context, userID uint, requesterID uint) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, `
+		DELETE FROM friendships
+		WHERE user_id = $1 AND friend_id = $2 AND status = $3
+	`, requesterID, userID, friendshipPending)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFriendRequestNotFound
+	}
+
+	return tx.Commit(c)
+}
+
+// RemoveFriend removes a friendship or a pending request in either direction.
+func RemoveFriend(c context.Context, userID uint, friendID uint) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, `
+		DELETE FROM friendships
+		WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
+	`, userID, friendID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFriendshipNotFound
+	}
+
+	return tx.Commit(c)
+}
+
+// GetFriendList returns the user's friends and pending requests. The presence
+// of friends is only set from players.is_spawned, to either spawned or offline.
+func GetFriendList(c context.Context, userID uint) (_ *game.FriendList, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT u.id, u.name, f.status, f.user_id = u.id, COALESCE(p.is_spawned, FALSE)
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+		LEFT JOIN players p ON p.user_id = u.id
+		WHERE f.user_id = $1 OR f.friend_id = $1
+		ORDER BY u.name;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := game.FriendList{
+		Friends:  []*game.Friend{},
+		Incoming: []*game.Friend{},
+		Outgoing: []*game.Friend{},
+	}
+	for rows.Next() {
+		var friend game.Friend
+		var status string
+		var incoming, spawned bool
+		if err := rows.Scan(&friend.UserID, &friend.Username, &status, &incoming, &spawned); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case status == friendshipAccepted:
+			friend.Presence = game.PresenceOffline
+			if spawned {
+				friend.Presence = game.PresenceSpawned
+			}
+			list.Friends = append(list.Friends, &friend)
+		case incoming:
+			list.Incoming = append(list.Incoming, &friend)
+		default:
+			list.Outgoing = append(list.Outgoing, &friend)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func GetFriendNames(c context.Context, userID uint) (_ []string, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT u.name
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+		WHERE (f.user_id = $1 OR f.friend_id = $1) AND f.status = $2;
+	`, userID, friendshipAccepted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
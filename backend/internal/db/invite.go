@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrInvalidInvite = errs.NotFound("invite is invalid, expired or revoked")
+
+func CreateClanInvite(
+	c context.Context,
+	clanID uint,
+	createdBy uint,
+	code string,
+	usesLeft *uint,
+	expiresAt *time.Time,
+) (_ *game.ClanInvite, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	var id uint
+	err = tx.QueryRow(c, `
+		INSERT INTO clan_invites (clan_id, code, created_by, uses_left, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, clanID, code, createdBy, usesLeft, expiresAt).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return &game.ClanInvite{
+		ID:        id,
+		ClanID:    clanID,
+		Code:      code,
+		CreatedBy: createdBy,
+		UsesLeft:  usesLeft,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// GetClanInvites returns the invites of a clan that can still be used.
+func GetClanInvites(c context.Context, clanID uint) (_ []*game.ClanInvite, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `
+		SELECT id, clan_id, code, created_by, uses_left, expires_at
+		FROM clan_invites
+		WHERE clan_id = $1
+			AND NOT revoked
+			AND (uses_left IS NULL OR uses_left > 0)
+			AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at;
+	`, clanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []*game.ClanInvite{}
+	for rows.Next() {
+		var invite game.ClanInvite
+		if err := rows.Scan(&invite.ID, &invite.ClanID, &invite.Code, &invite.CreatedBy, &invite.UsesLeft, &invite.ExpiresAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, &invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func RevokeClanInvite(c context.Context, clanID uint, code string) (err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, `
+		UPDATE clan_invites
+		SET revoked = TRUE
+		WHERE clan_id = $1 AND code = $2 AND NOT revoked
+	`, clanID, code)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidInvite
+	}
+
+	return tx.Commit(c)
+}
+
+// JoinClanWithInvite consumes one use of the invite and moves the user's
+// player into the invite's clan. It returns the ID of the joined clan.
+func JoinClanWithInvite(c context.Context, userID uint, code string) (_ uint, err error) {
+	defer wrapError(&err, "")
+
+	tx, err := conn.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var clanID uint
+	err = tx.QueryRow(c, `
+		UPDATE clan_invites
+		SET uses_left = uses_left - 1
+		WHERE code = $1
+			AND NOT revoked
+			AND (uses_left IS NULL OR uses_left > 0)
+			AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING clan_id
+	`, code).Scan(&clanID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrInvalidInvite
+		}
+		return 0, err
+	}
+
+	if _, err = tx.Exec(c, `
+		UPDATE players
+		SET clan_id = $1, clan_role = $3
+		WHERE user_id = $2;
+	`, clanID, userID, game.ClanRoleMember); err != nil {
+		return 0, err
+	}
+
+	if err = insertClanAuditEntry(c, tx, clanID, userID, nil, game.ClanActionJoin); err != nil {
+		return 0, err
+	}
+
+	return clanID, tx.Commit(c)
+}
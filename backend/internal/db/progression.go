@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+// GetPlayerPassives returns the levels of the player's passives by key.
+func GetPlayerPassives(c context.Context, playerID uint) (_ map[string]uint, err error) {
+	defer wrapError(&err, "")
+
+	rows, err := conn.Query(c, `
+		SELECT passive_key, level
+		FROM player_passives
+		WHERE player_id = $1
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passives := make(map[string]uint)
+	for rows.Next() {
+		var key string
+		var level uint
+		if err := rows.Scan(&key, &level); err != nil {
+			return nil, err
+		}
+		passives[key] = level
+	}
+
+	return passives, rows.Err()
+}
+
+// GetPlayerUpgrade returns the last level the player was offered an upgrade
+// for and the offer they have yet to choose from, if any.
+func GetPlayerUpgrade(c context.Context, playerID uint) (_ uint, _ *game.PlayerUpgrade, err error) {
+	defer wrapError(&err, "player not found")
+
+	var level uint
+	var offer []byte
+	if err := conn.QueryRow(c, `
+		SELECT upgrade_level, upgrade_offer
+		FROM players
+		WHERE id = $1
+	`, playerID).Scan(&level, &offer); err != nil {
+		return 0, nil, err
+	}
+
+	if offer == nil {
+		return level, nil, nil
+	}
+
+	var upgrade game.PlayerUpgrade
+	if err := json.Unmarshal(offer, &upgrade); err != nil {
+		return 0, nil, err
+	}
+	return level, &upgrade, nil
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"context"
+	"valley-of-survival-dawn-of-squares/internal/game"
+)
+
+// SaveRun records a run ended by the player's final death.
+func SaveRun(c context.Context, summary game.RunSummary) (err error) {
+	defer wrapError(&err, "")
+
+	_, err = conn.Exec(c, `
+		INSERT INTO runs (player_id, ticks, seconds, kills, experience_points, level, map_seed, map_layout,
+			difficulty, curses)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, summary.PlayerID, summary.Ticks, summary.Seconds, summary.Kills, summary.EXP, summary.Level,
+		int64(summary.Seed), summary.Layout, summary.Difficulty, append([]string{}, summary.Curses...))
+	return err
+}
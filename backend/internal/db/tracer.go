@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var queryDurations = metrics.NewHistogram("db_query_duration_seconds", "Time spent executing database queries.", metrics.DefaultBuckets, "statement", "status")
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at        time.Time
+	statement string
+}
+
+// queryTracer records the duration of every query, labelled by its statement
+// kind such as SELECT or UPDATE.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(c context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(c, queryStartKey{}, queryStart{at: time.Now(), statement: statementKind(data.SQL)})
+}
+
+func (queryTracer) TraceQueryEnd(c context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := c.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+	queryDurations.Observe(time.Since(start.at).Seconds(), start.statement, status)
+}
+
+func statementKind(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,811 @@
+// Package definitions loads the weapon classes, projectiles, passive items,
+// enemy archetypes, run modes and gameplay tuning from the JSON files of the
+// data directory.
+package definitions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+)
+
+// Version is the version of the definition files this server understands,
+// files of any other version are rejected.
+const Version = 1
+
+const (
+	WeaponsFile  = "weapons.json"
+	EnemiesFile  = "enemies.json"
+	PassivesFile = "passives.json"
+	ModesFile    = "modes.json"
+	TuningFile   = "tuning.json"
+)
+
+// Kinds of loot, each dropped as a pickup.
+const (
+	LootExp    = "exp"    // adds Amount EXP
+	LootHealth = "health" // restores Amount HP
+	LootMagnet = "magnet" // pulls every EXP pickup to the player
+	LootHaste  = "haste"  // Amount percent more move speed for Duration ticks
+	LootFury   = "fury"   // Amount percent more damage for Duration ticks
+	LootChest  = "chest"  // an extra level-up offer
+)
+
+// Patterns of the area attacks of bosses.
+const (
+	PatternTargets = "targets" // one area on each of the Count players closest to the boss
+	PatternRing    = "ring"    // Count areas evenly spread around the boss at Distance
+)
+
+type Projectile struct {
+	Key     string  `json:"key"`
+	Speed   float64 `json:"speed"`  // world units per tick
+	Size    uint    `json:"size"`   // world units
+	Pierce  uint    `json:"pierce"` // enemies passed through after the first hit
+	Pellets uint    `json:"pellets"`
+	Spread  float64 `json:"spread"` // degrees of the cone the pellets are fanned over
+}
+
+type Weapon struct {
+	ID          uint    `json:"id"` // ID of the weapon class in the database
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Icon        string  `json:"icon"`
+	Damage      []uint  `json:"damage"`               // per level starting at 1, the last one is used past the end
+	Range       float32 `json:"range"`                // player sizes
+	Cooldown    uint    `json:"cooldown"`             // ticks between two hits
+	Projectile  string  `json:"projectile,omitempty"` // key of the projectile fired, melee weapons have none
+	AoE         float32 `json:"aoe,omitempty"`        // radius of the area hit around the target, in player sizes
+}
+
+// MaxLevel is the highest level of the weapon, one per damage entry.
+func (w *Weapon) MaxLevel() uint {
+	return uint(len(w.Damage))
+}
+
+// DamageAt returns the damage of the weapon at the given level.
+func (w *Weapon) DamageAt(level uint) uint {
+	if level == 0 {
+		level = 1
+	}
+	return w.Damage[min(int(level), len(w.Damage))-1]
+}
+
+// Evolution turns a weapon at its max level into a stronger weapon once the
+// player also owns the passive.
+type Evolution struct {
+	Weapon  string `json:"weapon"`  // key of the weapon evolving
+	Passive string `json:"passive"` // key of the passive required
+	Into    string `json:"into"`    // key of the evolved weapon
+}
+
+// Stats modified by passive items and buffs.
+const (
+	StatMoveSpeed    = "move_speed"    // percent more move speed
+	StatMaxHP        = "max_hp"        // more max HP
+	StatCooldown     = "cooldown"      // percent less ticks between two hits
+	StatArea         = "area"          // percent larger areas of effect
+	StatArmor        = "armor"         // less damage taken per hit
+	StatPickupRadius = "pickup_radius" // world units further pickups are collected from
+	StatLuck         = "luck"          // percent higher loot chances
+	StatDamage       = "damage"        // percent more damage
+)
+
+var stats = []string{StatMoveSpeed, StatMaxHP, StatCooldown, StatArea, StatArmor, StatPickupRadius, StatLuck, StatDamage}
+
+// Passive is an item raising a stat by Value for each of its levels.
+type Passive struct {
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Icon        string  `json:"icon"`
+	Stat        string  `json:"stat"`
+	Value       float64 `json:"value"` // per level
+	MaxLevel    uint    `json:"max_level"`
+}
+
+// Difficulty is a mode players pick when they spawn, scaling the enemies
+// spawned around them and the EXP they collect, in percent of the base values.
+type Difficulty struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	EnemyHP     uint   `json:"enemy_hp"`
+	EnemyDamage uint   `json:"enemy_damage"`
+	EnemySpeed  uint   `json:"enemy_speed"`
+	SpawnRate   uint   `json:"spawn_rate"`
+	Exp         uint   `json:"exp"`
+}
+
+// Curse is a modifier players may opt into when they spawn, making their run
+// harder for more EXP. Its amounts are percent more than the difficulty.
+type Curse struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	EnemySpeed  uint   `json:"enemy_speed,omitempty"`
+	SpawnRate   uint   `json:"spawn_rate,omitempty"`
+	NoHealing   bool   `json:"no_healing,omitempty"` // health pickups heal nothing
+	Exp         uint   `json:"exp"`
+}
+
+// RunModifiers are the combined effects of the difficulty and curses of a
+// run, in percent of the base values.
+type RunModifiers struct {
+	EnemyHP     uint
+	EnemyDamage uint
+	EnemySpeed  uint
+	SpawnRate   uint
+	Exp         uint
+	NoHealing   bool
+}
+
+// NeutralModifiers change nothing, for runs of an unknown difficulty.
+var NeutralModifiers = RunModifiers{EnemyHP: 100, EnemyDamage: 100, EnemySpeed: 100, SpawnRate: 100, Exp: 100}
+
+type Loot struct {
+	Kind     string  `json:"kind"`
+	Amount   uint    `json:"amount"`
+	Chance   float64 `json:"chance"`             // between 0 and 1
+	Duration uint    `json:"duration,omitempty"` // ticks, for buffs
+}
+
+// IsBuff reports whether the loot is a temporary buff.
+func (l *Loot) IsBuff() bool {
+	return l.Kind == LootHaste || l.Kind == LootFury
+}
+
+type Enemy struct {
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Icon        string  `json:"icon"`
+	HP          uint    `json:"hp"`
+	Speed       uint    `json:"speed"` // world units per tick
+	Size        uint    `json:"size"`  // world units
+	Damage      uint    `json:"damage"`
+	Range       float32 `json:"range"`    // player sizes, melee enemies hit what they touch
+	Cooldown    uint    `json:"cooldown"` // ticks between two hits
+	Loot        []Loot  `json:"loot"`
+}
+
+// Boss is an enemy appearing Minute minutes into every run, near the player
+// whose run it is. It switches to the next of its phases once its HP drops to
+// the phase's threshold.
+type Boss struct {
+	Enemy
+	Minute uint        `json:"minute"`
+	Phases []BossPhase `json:"phases"`
+}
+
+type BossPhase struct {
+	Threshold uint       `json:"threshold"` // percent of max HP the phase starts at, 100 for the first one
+	Speed     uint       `json:"speed"`     // percent of the boss speed
+	Attack    BossAttack `json:"attack"`
+}
+
+// BossAttack is an area attack telegraphed Warning ticks before it hits.
+type BossAttack struct {
+	Pattern  string  `json:"pattern"`
+	Count    uint    `json:"count"`              // areas per attack
+	Radius   float32 `json:"radius"`             // player sizes
+	Distance float32 `json:"distance,omitempty"` // from the boss in player sizes, for rings
+	Damage   uint    `json:"damage"`
+	Warning  uint    `json:"warning"`  // ticks between the telegraph and the hit
+	Cooldown uint    `json:"cooldown"` // ticks between two attacks
+}
+
+// PhaseAt returns the index of the phase of a boss with the given percent of
+// its HP left.
+func (b *Boss) PhaseAt(percent uint) int {
+	phase := 0
+	for i, p := range b.Phases {
+		if percent <= p.Threshold {
+			phase = i
+		}
+	}
+	return phase
+}
+
+type PlayerTuning struct {
+	MaxHP uint `json:"max_hp"`
+	Speed uint `json:"speed"` // world units per tick
+}
+
+// SpawnerTuning controls how enemies are spawned around the players.
+type SpawnerTuning struct {
+	Interval    uint            `json:"interval"`     // ticks between two spawns
+	MaxEnemies  uint            `json:"max_enemies"`  // no spawns while the world has that many enemies
+	MinDistance uint            `json:"min_distance"` // world units from the player spawned around
+	MaxDistance uint            `json:"max_distance"`
+	Weights     map[string]uint `json:"weights"` // relative chance of each enemy key
+}
+
+// ExpCurve gives the EXP needed to level up, growing geometrically.
+type ExpCurve struct {
+	Base   uint    `json:"base"`   // EXP from level 1 to 2
+	Growth float64 `json:"growth"` // factor applied for each following level
+}
+
+// ToNextLevel returns the EXP needed to go from level to the next one.
+func (e ExpCurve) ToNextLevel(level uint) uint {
+	return uint(float64(e.Base) * math.Pow(e.Growth, float64(max(level, 1)-1)))
+}
+
+// LevelForExp returns the level reached with the given total EXP.
+func (e ExpCurve) LevelForExp(exp uint) uint {
+	level := uint(1)
+	for {
+		needed := e.ToNextLevel(level)
+		if needed == 0 || exp < needed {
+			return level
+		}
+		exp -= needed
+		level++
+	}
+}
+
+// PickupTuning controls the pickups dropped by enemies.
+type PickupTuning struct {
+	Lifetime    uint    `json:"lifetime"`     // ticks before an uncollected pickup despawns
+	Size        uint    `json:"size"`         // world units
+	MagnetSpeed float64 `json:"magnet_speed"` // world units per tick of pickups pulled by a magnet
+}
+
+// ReviveTuning controls what happens to players whose HP reaches zero.
+type ReviveTuning struct {
+	BleedOut        uint `json:"bleed_out"`        // ticks a downed player lasts without being revived
+	Duration        uint `json:"duration"`         // ticks an ally must stay nearby to revive them
+	Radius          uint `json:"radius"`           // world units between the centers of the ally and the downed player
+	HP              uint `json:"hp"`               // percent of the max HP revived players get back
+	RespawnCooldown uint `json:"respawn_cooldown"` // ticks after the final death before spawning again
+}
+
+// UpgradeTuning controls the offers made to players when they level up.
+type UpgradeTuning struct {
+	Choices     uint `json:"choices"`      // per offer, at most
+	MaxWeapons  uint `json:"max_weapons"`  // carried at once
+	MaxPassives uint `json:"max_passives"` // carried at once
+}
+
+// MapTuning controls the map made when the server starts, changes only apply
+// on the next start. Infinite worlds have no bounds and are generated in
+// chunks around the players, with Obstacles and Zones per chunk.
+type MapTuning struct {
+	Seed           uint64  `json:"seed"`             // of the generated map, 0 draws one
+	Layout         string  `json:"layout,omitempty"` // file of the maps directory loaded instead of generating a map
+	Infinite       bool    `json:"infinite"`
+	ChunkSize      uint    `json:"chunk_size"`  // world units, for infinite worlds
+	LoadRadius     uint    `json:"load_radius"` // chunks loaded around every player, for infinite worlds
+	Obstacles      uint    `json:"obstacles"`
+	ObstacleSize   [2]uint `json:"obstacle_size"` // min and max, world units
+	Zones          uint    `json:"zones"`
+	ZoneSize       [2]uint `json:"zone_size"`       // min and max, world units
+	Slow           uint    `json:"slow"`            // percent less move speed in generated slow zones
+	Damage         uint    `json:"damage"`          // HP lost in generated damage zones
+	DamageInterval uint    `json:"damage_interval"` // ticks between two hits of damage zones
+	SpawnAreas     uint    `json:"spawn_areas"`
+	SpawnAreaSize  uint    `json:"spawn_area_size"` // world units
+}
+
+// PartyTuning controls parties and how their members share the EXP they
+// collect. The EXP of a gem is split evenly between the members around the
+// collector, the gem being worth ShareBonus percent more per member past the
+// first.
+type PartyTuning struct {
+	MaxSize     uint `json:"max_size"`     // members, leader included
+	ShareRadius uint `json:"share_radius"` // world units around the collector
+	ShareBonus  uint `json:"share_bonus"`  // percent per member past the first
+}
+
+type Tuning struct {
+	TickRate uint          `json:"tick_rate"`
+	Player   PlayerTuning  `json:"player"`
+	Spawner  SpawnerTuning `json:"spawner"`
+	ExpCurve ExpCurve      `json:"exp_curve"`
+	Pickups  PickupTuning  `json:"pickups"`
+	Revive   ReviveTuning  `json:"revive"`
+	Upgrades UpgradeTuning `json:"upgrades"`
+	Map      MapTuning     `json:"map"`
+	Party    PartyTuning   `json:"party"`
+}
+
+// DefaultTuning is used until definitions are loaded.
+var DefaultTuning = Tuning{
+	TickRate: 60,
+	Player:   PlayerTuning{MaxHP: 100, Speed: 4},
+	ExpCurve: ExpCurve{Base: 20, Growth: 1.25},
+	Pickups:  PickupTuning{Lifetime: 1800, Size: 12, MagnetSpeed: 12},
+	Revive:   ReviveTuning{BleedOut: 1800, Duration: 180, Radius: 64, HP: 50, RespawnCooldown: 600},
+	Upgrades: UpgradeTuning{Choices: 3, MaxWeapons: 6, MaxPassives: 6},
+	Map:      MapTuning{DamageInterval: 30},
+	Party:    PartyTuning{MaxSize: 4},
+}
+
+type passivesFile struct {
+	Version  int        `json:"version"`
+	Passives []*Passive `json:"passives"`
+}
+
+type modesFile struct {
+	Version      int           `json:"version"`
+	Default      string        `json:"default"` // key of the difficulty of players who pick none
+	Difficulties []*Difficulty `json:"difficulties"`
+	Curses       []*Curse      `json:"curses"`
+}
+
+type tuningFile struct {
+	Version int `json:"version"`
+	Tuning
+}
+
+type weaponsFile struct {
+	Version     int           `json:"version"`
+	Projectiles []*Projectile `json:"projectiles"`
+	Weapons     []*Weapon     `json:"weapons"`
+	Evolutions  []*Evolution  `json:"evolutions"`
+}
+
+type enemiesFile struct {
+	Version int      `json:"version"`
+	Enemies []*Enemy `json:"enemies"`
+	Bosses  []*Boss  `json:"bosses"`
+}
+
+// Set is a complete and validated set of definitions.
+type Set struct {
+	Projectiles       []*Projectile
+	Weapons           []*Weapon
+	Evolutions        []*Evolution
+	Enemies           []*Enemy
+	Bosses            []*Boss
+	Passives          []*Passive
+	Difficulties      []*Difficulty
+	Curses            []*Curse
+	DefaultDifficulty string
+	Tuning            Tuning
+
+	projectiles  map[string]*Projectile
+	weapons      map[uint]*Weapon
+	weaponKeys   map[string]*Weapon
+	evolutions   map[uint]*Evolution // by ID of the weapon evolving
+	evolved      map[uint]*Weapon    // weapon evolving, by ID of the evolved weapon
+	enemies      map[string]*Enemy
+	bosses       map[string]*Boss
+	passives     map[string]*Passive
+	difficulties map[string]*Difficulty
+	curses       map[string]*Curse
+}
+
+func (s *Set) Projectile(key string) (*Projectile, bool) {
+	projectile, ok := s.projectiles[key]
+	return projectile, ok
+}
+
+func (s *Set) Weapon(id uint) (*Weapon, bool) {
+	weapon, ok := s.weapons[id]
+	return weapon, ok
+}
+
+// Evolution returns the recipe evolving the weapon and the weapon it evolves
+// into.
+func (s *Set) Evolution(weaponID uint) (*Evolution, *Weapon, bool) {
+	evolution, ok := s.evolutions[weaponID]
+	if !ok {
+		return nil, nil, false
+	}
+	return evolution, s.weaponKeys[evolution.Into], true
+}
+
+// EvolvedFrom returns the weapon evolving into the given one, evolved weapons
+// are only obtained through their recipe.
+func (s *Set) EvolvedFrom(weaponID uint) (*Weapon, bool) {
+	weapon, ok := s.evolved[weaponID]
+	return weapon, ok
+}
+
+func (s *Set) Enemy(key string) (*Enemy, bool) {
+	enemy, ok := s.enemies[key]
+	return enemy, ok
+}
+
+func (s *Set) Boss(key string) (*Boss, bool) {
+	boss, ok := s.bosses[key]
+	return boss, ok
+}
+
+// Archetype returns the enemy definition of a regular enemy or a boss.
+func (s *Set) Archetype(key string) (*Enemy, bool) {
+	if enemy, ok := s.enemies[key]; ok {
+		return enemy, true
+	}
+	if boss, ok := s.bosses[key]; ok {
+		return &boss.Enemy, true
+	}
+	return nil, false
+}
+
+func (s *Set) Passive(key string) (*Passive, bool) {
+	passive, ok := s.passives[key]
+	return passive, ok
+}
+
+func (s *Set) Difficulty(key string) (*Difficulty, bool) {
+	difficulty, ok := s.difficulties[key]
+	return difficulty, ok
+}
+
+func (s *Set) Curse(key string) (*Curse, bool) {
+	curse, ok := s.curses[key]
+	return curse, ok
+}
+
+// RunModifiers combines the difficulty with the curses, the percent more of
+// the curses adding up before applying to the difficulty. Unknown keys change
+// nothing.
+func (s *Set) RunModifiers(difficulty string, curses []string) RunModifiers {
+	modifiers := NeutralModifiers
+	if definition, ok := s.difficulties[difficulty]; ok {
+		modifiers = RunModifiers{
+			EnemyHP:     definition.EnemyHP,
+			EnemyDamage: definition.EnemyDamage,
+			EnemySpeed:  definition.EnemySpeed,
+			SpawnRate:   definition.SpawnRate,
+			Exp:         definition.Exp,
+		}
+	}
+
+	var speed, spawnRate, exp uint
+	for _, key := range curses {
+		if curse, ok := s.curses[key]; ok {
+			speed += curse.EnemySpeed
+			spawnRate += curse.SpawnRate
+			exp += curse.Exp
+			modifiers.NoHealing = modifiers.NoHealing || curse.NoHealing
+		}
+	}
+	modifiers.EnemySpeed = modifiers.EnemySpeed * (100 + speed) / 100
+	modifiers.SpawnRate = modifiers.SpawnRate * (100 + spawnRate) / 100
+	modifiers.Exp = modifiers.Exp * (100 + exp) / 100
+	return modifiers
+}
+
+var current atomic.Pointer[Set]
+
+func init() {
+	current.Store(&Set{
+		Tuning:       DefaultTuning,
+		projectiles:  make(map[string]*Projectile),
+		weapons:      make(map[uint]*Weapon),
+		weaponKeys:   make(map[string]*Weapon),
+		evolutions:   make(map[uint]*Evolution),
+		evolved:      make(map[uint]*Weapon),
+		enemies:      make(map[string]*Enemy),
+		bosses:       make(map[string]*Boss),
+		passives:     make(map[string]*Passive),
+		difficulties: make(map[string]*Difficulty),
+		curses:       make(map[string]*Curse),
+	})
+}
+
+// Current returns the definitions in use, the set is empty until one is
+// stored with SetCurrent.
+func Current() *Set {
+	return current.Load()
+}
+
+func SetCurrent(set *Set) {
+	current.Store(set)
+}
+
+func readFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Files returns the paths of the definition files of the directory.
+func Files(dir string) []string {
+	return []string{
+		filepath.Join(dir, WeaponsFile),
+		filepath.Join(dir, EnemiesFile),
+		filepath.Join(dir, PassivesFile),
+		filepath.Join(dir, ModesFile),
+		filepath.Join(dir, TuningFile),
+	}
+}
+
+// Load reads and validates the definition files of the directory.
+func Load(dir string) (*Set, error) {
+	var weapons weaponsFile
+	if err := readFile(filepath.Join(dir, WeaponsFile), &weapons); err != nil {
+		return nil, err
+	}
+
+	var enemies enemiesFile
+	if err := readFile(filepath.Join(dir, EnemiesFile), &enemies); err != nil {
+		return nil, err
+	}
+
+	var passives passivesFile
+	if err := readFile(filepath.Join(dir, PassivesFile), &passives); err != nil {
+		return nil, err
+	}
+
+	var modes modesFile
+	if err := readFile(filepath.Join(dir, ModesFile), &modes); err != nil {
+		return nil, err
+	}
+
+	var tuning tuningFile
+	if err := readFile(filepath.Join(dir, TuningFile), &tuning); err != nil {
+		return nil, err
+	}
+
+	set := &Set{
+		Projectiles:       weapons.Projectiles,
+		Weapons:           weapons.Weapons,
+		Evolutions:        weapons.Evolutions,
+		Enemies:           enemies.Enemies,
+		Bosses:            enemies.Bosses,
+		Passives:          passives.Passives,
+		Difficulties:      modes.Difficulties,
+		Curses:            modes.Curses,
+		DefaultDifficulty: modes.Default,
+		Tuning:            tuning.Tuning,
+		projectiles:       make(map[string]*Projectile),
+		weapons:           make(map[uint]*Weapon),
+		weaponKeys:        make(map[string]*Weapon),
+		evolutions:        make(map[uint]*Evolution),
+		evolved:           make(map[uint]*Weapon),
+		enemies:           make(map[string]*Enemy),
+		bosses:            make(map[string]*Boss),
+		passives:          make(map[string]*Passive),
+		difficulties:      make(map[string]*Difficulty),
+		curses:            make(map[string]*Curse),
+	}
+
+	var problems []error
+	problem := func(file string, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: %s", file, fmt.Sprintf(format, args...)))
+	}
+
+	if weapons.Version != Version {
+		problem(WeaponsFile, "version %d is not supported, expected %d", weapons.Version, Version)
+	}
+	if enemies.Version != Version {
+		problem(EnemiesFile, "version %d is not supported, expected %d", enemies.Version, Version)
+	}
+	if passives.Version != Version {
+		problem(PassivesFile, "version %d is not supported, expected %d", passives.Version, Version)
+	}
+	if modes.Version != Version {
+		problem(ModesFile, "version %d is not supported, expected %d", modes.Version, Version)
+	}
+	if tuning.Version != Version {
+		problem(TuningFile, "version %d is not supported, expected %d", tuning.Version, Version)
+	}
+
+	for _, projectile := range set.Projectiles {
+		switch {
+		case len(projectile.Key) == 0:
+			problem(WeaponsFile, "projectile without key")
+		case set.projectiles[projectile.Key] != nil:
+			problem(WeaponsFile, "duplicate projectile %q", projectile.Key)
+		case projectile.Speed <= 0 || projectile.Size == 0 || projectile.Pellets == 0:
+			problem(WeaponsFile, "projectile %q needs a speed, size and pellets", projectile.Key)
+		case projectile.Spread < 0 || projectile.Spread >= 360:
+			problem(WeaponsFile, "projectile %q spread must be between 0 and 360 degrees", projectile.Key)
+		}
+		set.projectiles[projectile.Key] = projectile
+	}
+
+	for _, weapon := range set.Weapons {
+		switch {
+		case weapon.ID == 0 || len(weapon.Key) == 0 || len(weapon.Name) == 0:
+			problem(WeaponsFile, "weapon %q needs an id, key and name", weapon.Key)
+		case set.weapons[weapon.ID] != nil || set.weaponKeys[weapon.Key] != nil:
+			problem(WeaponsFile, "duplicate weapon %d %q", weapon.ID, weapon.Key)
+		case len(weapon.Damage) == 0:
+			problem(WeaponsFile, "weapon %q needs damage for at least one level", weapon.Key)
+		case weapon.Range <= 0 || weapon.Cooldown == 0:
+			problem(WeaponsFile, "weapon %q needs a range and cooldown", weapon.Key)
+		case weapon.AoE < 0:
+			problem(WeaponsFile, "weapon %q aoe cannot be negative", weapon.Key)
+		case len(weapon.Projectile) != 0 && set.projectiles[weapon.Projectile] == nil:
+			problem(WeaponsFile, "weapon %q fires unknown projectile %q", weapon.Key, weapon.Projectile)
+		}
+		set.weapons[weapon.ID] = weapon
+		set.weaponKeys[weapon.Key] = weapon
+	}
+
+	checkEnemy := func(enemy *Enemy) {
+		switch {
+		case len(enemy.Key) == 0 || len(enemy.Name) == 0:
+			problem(EnemiesFile, "enemy %q needs a key and name", enemy.Key)
+		case set.enemies[enemy.Key] != nil || set.bosses[enemy.Key] != nil:
+			problem(EnemiesFile, "duplicate enemy %q", enemy.Key)
+		case enemy.HP == 0 || enemy.Size == 0 || enemy.Cooldown == 0:
+			problem(EnemiesFile, "enemy %q needs hp, size and cooldown", enemy.Key)
+		}
+		for _, loot := range enemy.Loot {
+			switch loot.Kind {
+			case LootExp, LootHealth:
+				if loot.Amount == 0 {
+					problem(EnemiesFile, "enemy %q %s loot needs an amount", enemy.Key, loot.Kind)
+				}
+			case LootHaste, LootFury:
+				if loot.Amount == 0 || loot.Duration == 0 {
+					problem(EnemiesFile, "enemy %q %s loot needs an amount and duration", enemy.Key, loot.Kind)
+				}
+			case LootMagnet, LootChest:
+			default:
+				problem(EnemiesFile, "enemy %q drops unknown loot %q", enemy.Key, loot.Kind)
+			}
+			if loot.Chance < 0 || loot.Chance > 1 {
+				problem(EnemiesFile, "enemy %q loot chance must be between 0 and 1", enemy.Key)
+			}
+		}
+	}
+
+	for _, enemy := range set.Enemies {
+		checkEnemy(enemy)
+		set.enemies[enemy.Key] = enemy
+	}
+
+	for _, boss := range set.Bosses {
+		checkEnemy(&boss.Enemy)
+		if boss.Minute == 0 {
+			problem(EnemiesFile, "boss %q needs the minute it appears at", boss.Key)
+		}
+		if len(boss.Phases) == 0 || boss.Phases[0].Threshold != 100 {
+			problem(EnemiesFile, "boss %q needs phases starting with a threshold of 100", boss.Key)
+		}
+		for i, phase := range boss.Phases {
+			attack := phase.Attack
+			switch {
+			case i != 0 && phase.Threshold >= boss.Phases[i-1].Threshold:
+				problem(EnemiesFile, "boss %q phase thresholds must decrease", boss.Key)
+			case attack.Pattern != PatternTargets && attack.Pattern != PatternRing:
+				problem(EnemiesFile, "boss %q attacks with unknown pattern %q", boss.Key, attack.Pattern)
+			case attack.Count == 0 || attack.Radius <= 0 || attack.Cooldown == 0:
+				problem(EnemiesFile, "boss %q attacks need a count, radius and cooldown", boss.Key)
+			}
+		}
+		set.bosses[boss.Key] = boss
+	}
+
+	for _, passive := range set.Passives {
+		switch {
+		case len(passive.Key) == 0 || len(passive.Name) == 0:
+			problem(PassivesFile, "passive %q needs a key and name", passive.Key)
+		case set.passives[passive.Key] != nil:
+			problem(PassivesFile, "duplicate passive %q", passive.Key)
+		case !slices.Contains(stats, passive.Stat):
+			problem(PassivesFile, "passive %q raises unknown stat %q", passive.Key, passive.Stat)
+		case passive.Value <= 0 || passive.MaxLevel == 0:
+			problem(PassivesFile, "passive %q needs a value and max_level", passive.Key)
+		}
+		set.passives[passive.Key] = passive
+	}
+
+	for _, difficulty := range set.Difficulties {
+		switch {
+		case len(difficulty.Key) == 0 || len(difficulty.Name) == 0:
+			problem(ModesFile, "difficulty %q needs a key and name", difficulty.Key)
+		case set.difficulties[difficulty.Key] != nil:
+			problem(ModesFile, "duplicate difficulty %q", difficulty.Key)
+		case difficulty.EnemyHP == 0 || difficulty.EnemyDamage == 0 || difficulty.EnemySpeed == 0 || difficulty.SpawnRate == 0 || difficulty.Exp == 0:
+			problem(ModesFile, "difficulty %q needs enemy_hp, enemy_damage, enemy_speed, spawn_rate and exp", difficulty.Key)
+		}
+		set.difficulties[difficulty.Key] = difficulty
+	}
+	if set.difficulties[set.DefaultDifficulty] == nil {
+		problem(ModesFile, "default difficulty %q is not defined", set.DefaultDifficulty)
+	}
+
+	for _, curse := range set.Curses {
+		switch {
+		case len(curse.Key) == 0 || len(curse.Name) == 0:
+			problem(ModesFile, "curse %q needs a key and name", curse.Key)
+		case set.curses[curse.Key] != nil:
+			problem(ModesFile, "duplicate curse %q", curse.Key)
+		case curse.EnemySpeed == 0 && curse.SpawnRate == 0 && !curse.NoHealing:
+			problem(ModesFile, "curse %q changes nothing", curse.Key)
+		}
+		set.curses[curse.Key] = curse
+	}
+
+	for _, evolution := range set.Evolutions {
+		weapon, into := set.weaponKeys[evolution.Weapon], set.weaponKeys[evolution.Into]
+		switch {
+		case weapon == nil || into == nil || weapon == into:
+			problem(WeaponsFile, "evolution of %q into %q needs two different known weapons", evolution.Weapon, evolution.Into)
+		case set.passives[evolution.Passive] == nil:
+			problem(WeaponsFile, "evolution of %q requires unknown passive %q", evolution.Weapon, evolution.Passive)
+		case set.evolutions[weapon.ID] != nil:
+			problem(WeaponsFile, "weapon %q has several evolutions", evolution.Weapon)
+		case set.evolved[into.ID] != nil:
+			problem(WeaponsFile, "weapon %q is evolved into by several weapons", evolution.Into)
+		default:
+			set.evolutions[weapon.ID] = evolution
+			set.evolved[into.ID] = weapon
+		}
+	}
+	for _, evolution := range set.Evolutions {
+		if into := set.weaponKeys[evolution.Into]; into != nil && set.evolutions[into.ID] != nil {
+			problem(WeaponsFile, "evolved weapon %q cannot evolve again", evolution.Into)
+		}
+	}
+
+	t := set.Tuning
+	if t.TickRate == 0 || t.TickRate > 240 {
+		problem(TuningFile, "tick_rate must be between 1 and 240")
+	}
+	if t.Player.MaxHP == 0 || t.Player.Speed == 0 {
+		problem(TuningFile, "player needs a max_hp and speed")
+	}
+	if t.Spawner.Interval == 0 || t.Spawner.MinDistance > t.Spawner.MaxDistance {
+		problem(TuningFile, "spawner needs an interval and min_distance up to max_distance")
+	}
+	for key := range t.Spawner.Weights {
+		if set.enemies[key] == nil {
+			problem(TuningFile, "spawner weights unknown enemy %q", key)
+		}
+	}
+	if t.ExpCurve.Base == 0 || t.ExpCurve.Growth < 1 {
+		problem(TuningFile, "exp_curve needs a base and a growth of at least 1")
+	}
+	if t.Pickups.Lifetime == 0 || t.Pickups.Size == 0 || t.Pickups.MagnetSpeed <= 0 {
+		problem(TuningFile, "pickups need a lifetime, size and magnet_speed")
+	}
+	if t.Upgrades.Choices == 0 || t.Upgrades.MaxWeapons == 0 {
+		problem(TuningFile, "upgrades need choices and max_weapons")
+	}
+	m := t.Map
+	if m.Obstacles != 0 && (m.ObstacleSize[0] == 0 || m.ObstacleSize[0] > m.ObstacleSize[1]) {
+		problem(TuningFile, "map obstacle_size needs a min of at least 1 up to the max")
+	}
+	if m.Zones != 0 && (m.ZoneSize[0] == 0 || m.ZoneSize[0] > m.ZoneSize[1]) {
+		problem(TuningFile, "map zone_size needs a min of at least 1 up to the max")
+	}
+	if m.Zones != 0 && (m.Slow == 0 || m.Slow > 100 || m.Damage == 0) {
+		problem(TuningFile, "map zones need a slow between 1 and 100 percent and damage")
+	}
+	if m.Infinite && (m.ChunkSize == 0 || len(m.Layout) != 0) {
+		problem(TuningFile, "infinite maps need a chunk_size and cannot load a layout")
+	}
+	if m.Infinite && m.ChunkSize*m.LoadRadius < t.Spawner.MaxDistance {
+		problem(TuningFile, "map load_radius chunks must cover the spawner max_distance")
+	}
+	if (m.SpawnAreas != 0 && m.SpawnAreaSize == 0) || m.DamageInterval == 0 {
+		problem(TuningFile, "map needs a spawn_area_size and damage_interval")
+	}
+	if t.Revive.Duration == 0 || t.Revive.HP == 0 || t.Revive.HP > 100 {
+		problem(TuningFile, "revive needs a duration and hp between 1 and 100 percent")
+	}
+	if t.Party.MaxSize < 2 {
+		problem(TuningFile, "party max_size must be at least 2")
+	}
+
+	if len(problems) != 0 {
+		return nil, fmt.Errorf("invalid definitions in %s: %w", dir, errors.Join(problems...))
+	}
+
+	return set, nil
+}
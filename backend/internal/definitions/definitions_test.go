@@ -0,0 +1,253 @@
+package definitions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const dataDir = "../../data"
+
+func TestLoadData(t *testing.T) {
+	set, err := Load(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, weapon := range set.Weapons {
+		if found, ok := set.Weapon(weapon.ID); !ok || found != weapon {
+			t.Errorf("weapon %q is not indexed", weapon.Key)
+		}
+	}
+	for _, evolution := range set.Evolutions {
+		weapon := set.weaponKeys[evolution.Weapon]
+		if _, into, ok := set.Evolution(weapon.ID); !ok || into.Key != evolution.Into {
+			t.Errorf("evolution of %q is not indexed", evolution.Weapon)
+		}
+	}
+	if _, ok := set.Difficulty(set.DefaultDifficulty); !ok {
+		t.Errorf("default difficulty %q is not indexed", set.DefaultDifficulty)
+	}
+}
+
+// copyData copies the data directory to a temporary one and returns it.
+func copyData(t *testing.T) string {
+	dir := t.TempDir()
+	for _, path := range Files(dataDir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// editFile decodes a definition file of the directory, lets edit change it and
+// writes it back.
+func editFile(t *testing.T, dir string, file string, edit func(data map[string]any)) {
+	path := filepath.Join(dir, file)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatal(err)
+	}
+	edit(data)
+
+	if content, err = json.Marshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// item returns the object at the index of the list under the key.
+func item(data map[string]any, key string, i int) map[string]any {
+	return data[key].([]any)[i].(map[string]any)
+}
+
+// object returns the object under the key.
+func object(data map[string]any, key string) map[string]any {
+	return data[key].(map[string]any)
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		edit func(data map[string]any)
+		want string
+	}{
+		// Versions
+		{"weapons version", WeaponsFile, func(d map[string]any) { d["version"] = 2 }, "weapons.json: version 2 is not supported"},
+		{"enemies version", EnemiesFile, func(d map[string]any) { d["version"] = 0 }, "enemies.json: version 0 is not supported"},
+		{"passives version", PassivesFile, func(d map[string]any) { d["version"] = 2 }, "passives.json: version 2"},
+		{"modes version", ModesFile, func(d map[string]any) { d["version"] = 2 }, "modes.json: version 2"},
+		{"tuning version", TuningFile, func(d map[string]any) { d["version"] = 2 }, "tuning.json: version 2"},
+
+		// Decoding
+		{"unknown field", PassivesFile, func(d map[string]any) { item(d, "passives", 0)["bonus"] = 1 }, `unknown field "bonus"`},
+		{"wrong type", TuningFile, func(d map[string]any) { d["tick_rate"] = "fast" }, "tuning.json"},
+
+		// Projectiles
+		{"projectile without key", WeaponsFile, func(d map[string]any) { item(d, "projectiles", 0)["key"] = "" }, "projectile without key"},
+		{"projectile without speed", WeaponsFile, func(d map[string]any) { item(d, "projectiles", 0)["speed"] = 0 }, "needs a speed, size and pellets"},
+		{"projectile spread", WeaponsFile, func(d map[string]any) { item(d, "projectiles", 0)["spread"] = 360 }, "spread must be between 0 and 360"},
+		{"duplicate projectile", WeaponsFile, func(d map[string]any) {
+			item(d, "projectiles", 1)["key"] = item(d, "projectiles", 0)["key"]
+		}, "duplicate projectile"},
+
+		// Weapons and their levels
+		{"weapon without id", WeaponsFile, func(d map[string]any) { item(d, "weapons", 0)["id"] = 0 }, "needs an id, key and name"},
+		{"duplicate weapon id", WeaponsFile, func(d map[string]any) { item(d, "weapons", 1)["id"] = item(d, "weapons", 0)["id"] }, "duplicate weapon"},
+		{"weapon without levels", WeaponsFile, func(d map[string]any) { item(d, "weapons", 0)["damage"] = []any{} }, "needs damage for at least one level"},
+		{"weapon without cooldown", WeaponsFile, func(d map[string]any) { item(d, "weapons", 0)["cooldown"] = 0 }, "needs a range and cooldown"},
+		{"weapon negative aoe", WeaponsFile, func(d map[string]any) { item(d, "weapons", 0)["aoe"] = -1 }, "aoe cannot be negative"},
+		{"unknown projectile", WeaponsFile, func(d map[string]any) { item(d, "weapons", 0)["projectile"] = "laser" }, `unknown projectile "laser"`},
+
+		// Evolutions
+		{"evolution of unknown weapon", WeaponsFile, func(d map[string]any) { item(d, "evolutions", 0)["weapon"] = "spoon" }, "needs two different known weapons"},
+		{"evolution into itself", WeaponsFile, func(d map[string]any) {
+			item(d, "evolutions", 0)["into"] = item(d, "evolutions", 0)["weapon"]
+		}, "needs two different known weapons"},
+		{"evolution unknown passive", WeaponsFile, func(d map[string]any) { item(d, "evolutions", 0)["passive"] = "cape" }, `unknown passive "cape"`},
+		{"several evolutions", WeaponsFile, func(d map[string]any) {
+			item(d, "evolutions", 1)["weapon"] = item(d, "evolutions", 0)["weapon"]
+		}, "has several evolutions"},
+		{"several evolving into", WeaponsFile, func(d map[string]any) {
+			item(d, "evolutions", 1)["into"] = item(d, "evolutions", 0)["into"]
+		}, "is evolved into by several weapons"},
+		{"evolving again", WeaponsFile, func(d map[string]any) {
+			item(d, "evolutions", 1)["weapon"] = item(d, "evolutions", 0)["into"]
+		}, "cannot evolve again"},
+
+		// Enemies and bosses
+		{"enemy without hp", EnemiesFile, func(d map[string]any) { item(d, "enemies", 0)["hp"] = 0 }, "needs hp, size and cooldown"},
+		{"duplicate enemy", EnemiesFile, func(d map[string]any) { item(d, "bosses", 0)["key"] = item(d, "enemies", 0)["key"] }, "duplicate enemy"},
+		{"unknown loot", EnemiesFile, func(d map[string]any) {
+			item(d, "enemies", 0)["loot"] = []any{map[string]any{"kind": "gold", "amount": 1, "chance": 1}}
+		}, `unknown loot "gold"`},
+		{"loot without amount", EnemiesFile, func(d map[string]any) {
+			item(d, "enemies", 0)["loot"] = []any{map[string]any{"kind": "exp", "chance": 1}}
+		}, "exp loot needs an amount"},
+		{"buff loot without duration", EnemiesFile, func(d map[string]any) {
+			item(d, "enemies", 0)["loot"] = []any{map[string]any{"kind": "haste", "amount": 10, "chance": 1}}
+		}, "needs an amount and duration"},
+		{"loot chance", EnemiesFile, func(d map[string]any) {
+			item(d, "enemies", 0)["loot"] = []any{map[string]any{"kind": "magnet", "chance": 2}}
+		}, "chance must be between 0 and 1"},
+		{"boss without minute", EnemiesFile, func(d map[string]any) { item(d, "bosses", 0)["minute"] = 0 }, "needs the minute it appears at"},
+		{"boss first phase", EnemiesFile, func(d map[string]any) {
+			item(item(d, "bosses", 0), "phases", 0)["threshold"] = 90
+		}, "phases starting with a threshold of 100"},
+		{"boss increasing phases", EnemiesFile, func(d map[string]any) {
+			item(item(d, "bosses", 0), "phases", 1)["threshold"] = 100
+		}, "phase thresholds must decrease"},
+		{"boss attack pattern", EnemiesFile, func(d map[string]any) {
+			object(item(item(d, "bosses", 0), "phases", 0), "attack")["pattern"] = "spiral"
+		}, `unknown pattern "spiral"`},
+
+		// Passives
+		{"passive unknown stat", PassivesFile, func(d map[string]any) { item(d, "passives", 0)["stat"] = "charm" }, `unknown stat "charm"`},
+		{"passive without max level", PassivesFile, func(d map[string]any) { item(d, "passives", 0)["max_level"] = 0 }, "needs a value and max_level"},
+		{"duplicate passive", PassivesFile, func(d map[string]any) { item(d, "passives", 1)["key"] = item(d, "passives", 0)["key"] }, "duplicate passive"},
+
+		// Modes
+		{"unknown default difficulty", ModesFile, func(d map[string]any) { d["default"] = "insane" }, `default difficulty "insane"`},
+		{"difficulty without exp", ModesFile, func(d map[string]any) { item(d, "difficulties", 0)["exp"] = 0 }, "needs enemy_hp"},
+		{"duplicate curse", ModesFile, func(d map[string]any) { item(d, "curses", 1)["key"] = item(d, "curses", 0)["key"] }, "duplicate curse"},
+		{"curse changing nothing", ModesFile, func(d map[string]any) {
+			d["curses"] = []any{map[string]any{"key": "dull", "name": "Dull", "description": "", "exp": 10}}
+		}, `curse "dull" changes nothing`},
+
+		// Tuning
+		{"tick rate", TuningFile, func(d map[string]any) { d["tick_rate"] = 0 }, "tick_rate must be between 1 and 240"},
+		{"spawner distances", TuningFile, func(d map[string]any) { object(d, "spawner")["min_distance"] = 100000 }, "min_distance up to max_distance"},
+		{"spawner unknown enemy", TuningFile, func(d map[string]any) { object(d, "spawner")["weights"] = map[string]any{"ghost": 1} }, `unknown enemy "ghost"`},
+		{"exp curve growth", TuningFile, func(d map[string]any) { object(d, "exp_curve")["growth"] = 0.5 }, "growth of at least 1"},
+		{"map obstacle sizes", TuningFile, func(d map[string]any) { object(d, "map")["obstacle_size"] = []any{200, 100} }, "obstacle_size needs a min"},
+		{"map slow", TuningFile, func(d map[string]any) { object(d, "map")["slow"] = 120 }, "slow between 1 and 100"},
+		{"infinite map layout", TuningFile, func(d map[string]any) {
+			object(d, "map")["infinite"] = true
+			object(d, "map")["layout"] = "crossroads.json"
+		}, "cannot load a layout"},
+		{"infinite map load radius", TuningFile, func(d map[string]any) {
+			object(d, "map")["infinite"] = true
+			object(d, "map")["load_radius"] = 0
+		}, "must cover the spawner max_distance"},
+		{"revive hp", TuningFile, func(d map[string]any) { object(d, "revive")["hp"] = 150 }, "hp between 1 and 100"},
+		{"party size", TuningFile, func(d map[string]any) { object(d, "party")["max_size"] = 1 }, "party max_size must be at least 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := copyData(t)
+			editFile(t, dir, test.file, test.edit)
+
+			set, err := Load(dir)
+			if err == nil {
+				t.Fatalf("Load accepted the definitions")
+			}
+			if set != nil {
+				t.Error("Load returned a set along with an error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %q does not mention %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	dir := copyData(t)
+	if err := os.Remove(filepath.Join(dir, ModesFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Fatal("Load accepted a directory without modes.json")
+	}
+}
+
+func TestRunModifiers(t *testing.T) {
+	set := &Set{
+		difficulties: map[string]*Difficulty{
+			"hard": {Key: "hard", EnemyHP: 150, EnemyDamage: 125, EnemySpeed: 110, SpawnRate: 120, Exp: 150},
+		},
+		curses: map[string]*Curse{
+			"swarm":  {Key: "swarm", SpawnRate: 50, Exp: 25},
+			"haste":  {Key: "haste", EnemySpeed: 20, Exp: 10},
+			"famine": {Key: "famine", NoHealing: true, Exp: 15},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		difficulty string
+		curses     []string
+		want       RunModifiers
+	}{
+		{"unknown difficulty is neutral", "", nil, NeutralModifiers},
+		{"difficulty", "hard", nil, RunModifiers{150, 125, 110, 120, 150, false}},
+		{"curse on neutral", "", []string{"swarm"}, RunModifiers{100, 100, 100, 150, 125, false}},
+		{"curse on difficulty", "hard", []string{"swarm"}, RunModifiers{150, 125, 110, 180, 187, false}},
+		{"curses add up before applying", "hard", []string{"swarm", "haste", "famine"}, RunModifiers{150, 125, 132, 180, 225, true}},
+		{"unknown curse changes nothing", "hard", []string{"nope"}, RunModifiers{150, 125, 110, 120, 150, false}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := set.RunModifiers(test.difficulty, test.curses); got != test.want {
+				t.Errorf("RunModifiers(%q, %v) = %+v, want %+v", test.difficulty, test.curses, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package definitions
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// MapsDir is the directory of the data directory holding the map layouts.
+const MapsDir = "maps"
+
+// Kinds of terrain zones.
+const (
+	ZoneSlow   = "slow"   // Amount percent less move speed
+	ZoneDamage = "damage" // Amount HP lost every damage_interval ticks
+)
+
+// Area is a square of the map, Position being its center.
+type Area struct {
+	Position [2]int `json:"position"`
+	Size     uint   `json:"size"` // world units
+}
+
+// Zone is an area of terrain slowing down or hurting what stands in it.
+type Zone struct {
+	Area
+	Kind   string `json:"kind"`
+	Amount uint   `json:"amount"`
+}
+
+// Layout is the static content of a map: obstacles blocking movement and
+// projectiles, terrain zones and the areas players are spawned in when their
+// position is blocked.
+type Layout struct {
+	Obstacles  []Area `json:"obstacles"`
+	Zones      []Zone `json:"zones"`
+	SpawnAreas []Area `json:"spawn_areas"`
+}
+
+type layoutFile struct {
+	Version int `json:"version"`
+	Layout
+}
+
+// LoadLayout reads and validates the layout file of the maps directory, for a
+// square world of the given size.
+func LoadLayout(dir string, name string, worldSize int) (Layout, error) {
+	path := filepath.Join(dir, MapsDir, name)
+
+	var file layoutFile
+	if err := readFile(path, &file); err != nil {
+		return Layout{}, err
+	}
+
+	var problems []error
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+
+	if file.Version != Version {
+		problem("version %d is not supported, expected %d", file.Version, Version)
+	}
+
+	inside := func(area Area) bool {
+		low, high := int(area.Size/2), int(area.Size-area.Size/2)
+		return area.Size != 0 &&
+			area.Position[0] >= low && area.Position[0]+high <= worldSize &&
+			area.Position[1] >= low && area.Position[1]+high <= worldSize
+	}
+	for i, obstacle := range file.Obstacles {
+		if !inside(obstacle) {
+			problem("obstacle %d needs a size and must be inside the world", i)
+		}
+	}
+	for i, zone := range file.Zones {
+		switch {
+		case !inside(zone.Area):
+			problem("zone %d needs a size and must be inside the world", i)
+		case zone.Kind != ZoneSlow && zone.Kind != ZoneDamage:
+			problem("zone %d is of unknown kind %q", i, zone.Kind)
+		case zone.Amount == 0 || (zone.Kind == ZoneSlow && zone.Amount > 100):
+			problem("zone %d needs an amount, up to 100 percent for slow zones", i)
+		}
+	}
+	for i, area := range file.SpawnAreas {
+		if !inside(area) {
+			problem("spawn area %d needs a size and must be inside the world", i)
+		}
+	}
+
+	if len(problems) != 0 {
+		return Layout{}, fmt.Errorf("invalid map layout in %s: %w", path, errors.Join(problems...))
+	}
+	return file.Layout, nil
+}
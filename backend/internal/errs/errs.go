@@ -0,0 +1,98 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors, also used as the code of JSON error responses.
+const (
+	KindInternal         = "internal"
+	KindValidation       = "validation"
+	KindUnauthorized     = "unauthorized"
+	KindForbidden        = "forbidden"
+	KindNotFound         = "not_found"
+	KindMethodNotAllowed = "method_not_allowed"
+	KindConflict         = "conflict"
+	KindTooLarge         = "too_large"
+)
+
+type Error struct {
+	Kind    string
+	Message string
+	Details map[string]any
+	Err     error // underlying cause, never exposed to clients
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same kind and message so that sentinel errors
+// compare equal to copies carrying details or a cause.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Message == e.Message
+}
+
+// WithDetails returns a copy of the error carrying the given details.
+func (e *Error) WithDetails(details map[string]any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+func New(kind string, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func Validation(message string) *Error {
+	return New(KindValidation, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(KindUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(KindForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+func MethodNotAllowed() *Error {
+	return New(KindMethodNotAllowed, "method not allowed")
+}
+
+func Conflict(message string) *Error {
+	return New(KindConflict, message)
+}
+
+// Internal wraps an unexpected error, its message is hidden from clients.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Message: "internal server error", Err: err}
+}
+
+// As returns err as a domain error, wrapping it as an internal error if it
+// is not one already.
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
+
+// KindOf returns the kind of err, KindInternal if it is not a domain error.
+func KindOf(err error) string {
+	return As(err).Kind
+}
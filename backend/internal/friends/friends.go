@@ -0,0 +1,137 @@
+package friends
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/session"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+var presenceMu sync.Mutex
+var lastPresence = make(map[string]string)
+
+// OnlineSessions maps the usernames of all connected clients to their sessions.
+func OnlineSessions() map[string][]string {
+	online := make(map[string][]string)
+	for _, sessionID := range ws.GetHub().SessionIDs() {
+		if username, ok := session.GetUsername(sessionID); ok {
+			online[username] = append(online[username], sessionID)
+		}
+	}
+	return online
+}
+
+// GetFriendList returns the user's friends with their current presence.
+func GetFriendList(c context.Context, userID uint) (*game.FriendList, error) {
+	list, err := db.GetFriendList(c, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	online := OnlineSessions()
+	for _, friend := range list.Friends {
+		if _, ok := online[friend.Username]; !ok {
+			friend.Presence = game.PresenceOffline
+		} else if friend.Presence != game.PresenceSpawned {
+			friend.Presence = game.PresenceOnline
+		}
+	}
+
+	return list, nil
+}
+
+// presenceOf returns the current presence of the user.
+func presenceOf(c context.Context, user *game.User, online map[string][]string) string {
+	if _, ok := online[user.Username]; !ok {
+		return game.PresenceOffline
+	}
+	if spawned, err := db.IsPlayerSpawned(c, user.ID); err == nil && spawned {
+		return game.PresenceSpawned
+	}
+	return game.PresenceOnline
+}
+
+// pushPresence sends the presence of the user to the connected clients of the
+// recipients.
+func pushPresence(user *game.User, presence string, recipients []string, online map[string][]string) {
+	friend := game.Friend{UserID: user.ID, Username: user.Username, Presence: presence}
+	hub := ws.GetHub()
+	for _, name := range recipients {
+		for _, sessionID := range online[name] {
+			hub.SendDirect(sessionID, ws.Message{Type: game.ServerFriendPresence, Data: friend})
+		}
+	}
+}
+
+// PublishPresence recomputes the presence of a user and pushes it to their
+// connected friends if it changed. It should be called whenever the user
+// connects, disconnects, spawns or despawns.
+func PublishPresence(username string) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := db.GetUserByName(c, username)
+	if err != nil {
+		log.Println("Error loading user for presence:", err)
+		return
+	}
+
+	online := OnlineSessions()
+	presence := presenceOf(c, user, online)
+
+	presenceMu.Lock()
+	if lastPresence[username] == presence {
+		presenceMu.Unlock()
+		return
+	}
+	if presence == game.PresenceOffline {
+		delete(lastPresence, username)
+	} else {
+		lastPresence[username] = presence
+	}
+	presenceMu.Unlock()
+
+	names, err := db.GetFriendNames(c, user.ID)
+	if err != nil {
+		log.Println("Error loading friends for presence:", err)
+		return
+	}
+	pushPresence(user, presence, names, online)
+}
+
+// PushPresenceTo pushes the current presence of the user to the friend even
+// if it did not change, to be called when they just became friends.
+func PushPresenceTo(username string, friend string) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := db.GetUserByName(c, username)
+	if err != nil {
+		log.Println("Error loading user for presence:", err)
+		return
+	}
+
+	online := OnlineSessions()
+	pushPresence(user, presenceOf(c, user, online), []string{friend}, online)
+}
+
+// NotifyFriendRequest pushes a friend request from the sender to the
+// recipient's connected clients.
+func NotifyFriendRequest(recipient string, sender game.Friend) {
+	hub := ws.GetHub()
+	for _, sessionID := range OnlineSessions()[recipient] {
+		hub.SendDirect(sessionID, ws.Message{Type: game.ServerFriendRequest, Data: sender})
+	}
+}
+
+func HandleConnect(client *ws.Client) {
+	PublishPresence(client.Username)
+}
+
+func HandleDisconnect(client *ws.Client) {
+	PublishPresence(client.Username)
+}
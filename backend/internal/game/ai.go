@@ -0,0 +1,70 @@
+package game
+
+// updateAI points every chasing enemy towards the closest player, following
+// the player's flow field around obstacles and heading straight at them once
+// in their cell or out of the field's reach.
+func updateAI(w *World, tick uint64) {
+	chased := make(map[EntityID]struct{})
+	w.AIs.Each(func(id EntityID, ai *AI) {
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			return
+		}
+		motion, ok := w.Motions.Get(id)
+		if !ok {
+			return
+		}
+
+		switch ai.Kind {
+		case AIChase:
+			ai.Target = w.closestPlayer(transform.Position)
+			if ai.Target == 0 {
+				return
+			}
+
+			chased[ai.Target] = struct{}{}
+			if field := w.flowField(ai.Target); field != nil {
+				if direction, ok := field.direction(transform.Position); ok {
+					motion.Direction = direction
+					return
+				}
+			}
+
+			target, _ := w.Transforms.Get(ai.Target)
+			for axis := range 2 {
+				motion.Direction[axis] = sign(target.Position[axis] - transform.Position[axis])
+			}
+		}
+	})
+	w.pruneFlowFields(chased)
+}
+
+func (w *World) closestPlayer(position [2]int) EntityID {
+	var closest EntityID
+	closestDistance := -1
+
+	w.Players.Each(func(id EntityID, _ *Player) {
+		transform, ok := w.Transforms.Get(id)
+		if !ok || w.IsDowned(id) {
+			return
+		}
+
+		dx := transform.Position[0] - position[0]
+		dy := transform.Position[1] - position[1]
+		if distance := dx*dx + dy*dy; closestDistance < 0 || distance < closestDistance {
+			closest, closestDistance = id, distance
+		}
+	})
+
+	return closest
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
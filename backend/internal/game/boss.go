@@ -0,0 +1,217 @@
+package game
+
+import (
+	"math"
+	"sort"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// updateBosses spawns the bosses due in the players' runs, moves the bosses to
+// the phase of their HP, telegraphs their area attacks and lands the strikes
+// whose warning ran out.
+func updateBosses(w *World, tick uint64) {
+	set := definitions.Current()
+	w.spawnBosses(set, tick)
+
+	var bars []BossHealth
+	var telegraphs []Telegraph
+	w.Bosses.Each(func(id EntityID, boss *Boss) {
+		ai, _ := w.AIs.Get(id)
+		definition, ok := set.Boss(ai.Archetype)
+		if !ok {
+			return
+		}
+		health, ok := w.Healths.Get(id)
+		if !ok || health.MaxHP == 0 {
+			return
+		}
+
+		// phases only move forward, even if the boss were healed
+		changed := health.HP != boss.HP
+		if phase := definition.PhaseAt(health.HP * 100 / health.MaxHP); phase > boss.Phase {
+			w.enterPhase(id, boss, definition, phase)
+			boss.Cooldown = 0
+			changed = true
+		}
+		if changed {
+			boss.HP = health.HP
+			bars = append(bars, w.BossState(id))
+		}
+		if health.HP == 0 {
+			return
+		}
+
+		if boss.Cooldown != 0 {
+			boss.Cooldown--
+			return
+		}
+		attack := definition.Phases[boss.Phase].Attack
+		boss.Cooldown = attack.Cooldown
+		telegraphs = append(telegraphs, w.telegraph(id, attack)...)
+	})
+
+	struck, damaged := w.landStrikes()
+
+	if len(bars) != 0 {
+		w.Emit(ServerBossHealth, bars)
+	}
+	if len(telegraphs) != 0 {
+		w.Emit(ServerBossTelegraph, telegraphs)
+	}
+	if len(struck) != 0 {
+		w.Emit(ServerBossStrike, struck)
+	}
+	if len(damaged) != 0 {
+		w.Emit(ServerDamagePlayers, damaged)
+	}
+}
+
+// spawnBosses spawns near every player standing the bosses whose minute their
+// run reached since the last tick.
+func (w *World) spawnBosses(set *definitions.Set, tick uint64) {
+	w.Runs.Each(func(id EntityID, run *Run) {
+		if w.IsDowned(id) {
+			return
+		}
+		minute := uint(time.Duration(tick-run.StartTick) * TickInterval() / time.Minute)
+		if minute <= run.BossMinute {
+			return
+		}
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			return
+		}
+
+		for _, boss := range set.Bosses {
+			if boss.Minute > run.BossMinute && boss.Minute <= minute {
+				w.SpawnBoss(w.spawnPosition(transform.Position, boss.Size), boss, w.runModifiers(id))
+			}
+		}
+		run.BossMinute = minute
+	})
+}
+
+// enterPhase switches the boss to the phase, which sets its speed.
+func (w *World) enterPhase(id EntityID, boss *Boss, definition *definitions.Boss, phase int) {
+	boss.Phase = phase
+	if motion, ok := w.Motions.Get(id); ok {
+		motion.Speed = definition.Speed * definition.Phases[phase].Speed / 100
+	}
+}
+
+// BossState builds the health bar sent to clients from the boss's components.
+func (w *World) BossState(id EntityID) BossHealth {
+	bar := BossHealth{ID: id}
+	if ai, ok := w.AIs.Get(id); ok {
+		bar.Kind = ai.Archetype
+		if definition, ok := definitions.Current().Boss(ai.Archetype); ok {
+			bar.Name = definition.Name
+		}
+	}
+	if health, ok := w.Healths.Get(id); ok {
+		bar.HP, bar.MaxHP = health.HP, health.MaxHP
+	}
+	if boss, ok := w.Bosses.Get(id); ok {
+		bar.Phase = boss.Phase
+	}
+	return bar
+}
+
+// telegraph places the areas of the boss's attack, which hit once their
+// warning runs out.
+func (w *World) telegraph(id EntityID, attack definitions.BossAttack) []Telegraph {
+	transform, ok := w.Transforms.Get(id)
+	if !ok {
+		return nil
+	}
+
+	var centers [][2]int
+	switch attack.Pattern {
+	case definitions.PatternTargets:
+		centers = w.closestPlayers(transform.Position, attack.Count)
+	case definitions.PatternRing:
+		distance := float64(attack.Distance) * PlayerSize
+		for i := range attack.Count {
+			angle := 2 * math.Pi * float64(i) / float64(attack.Count)
+			var center [2]int
+			for axis, offset := range [2]float64{math.Cos(angle), math.Sin(angle)} {
+				center[axis] = w.clamp(transform.Position[axis]+int(math.Round(offset*distance)), 1)
+			}
+			centers = append(centers, center)
+		}
+	}
+
+	radius := float64(attack.Radius) * PlayerSize
+	damage := percent(attack.Damage, w.modifiers(id).get(definitions.StatDamage))
+	telegraphs := make([]Telegraph, 0, len(centers))
+	for _, center := range centers {
+		strike := w.NewEntity()
+		w.Transforms.Add(strike, Transform{Position: center})
+		w.Strikes.Add(strike, Strike{Source: id, Radius: radius, Damage: damage, Ticks: attack.Warning})
+
+		telegraphs = append(telegraphs, Telegraph{
+			ID:       strike,
+			BossID:   id,
+			Position: center,
+			Radius:   radius,
+			Damage:   damage,
+			Ticks:    attack.Warning,
+		})
+	}
+	return telegraphs
+}
+
+// closestPlayers returns the positions of up to count standing players
+// closest to the position.
+func (w *World) closestPlayers(position [2]int, count uint) [][2]int {
+	var positions [][2]int
+	w.Players.Each(func(id EntityID, _ *Player) {
+		if transform, ok := w.Transforms.Get(id); ok && !w.IsDowned(id) {
+			positions = append(positions, transform.Position)
+		}
+	})
+
+	distance := func(p [2]int) int {
+		dx, dy := p[0]-position[0], p[1]-position[1]
+		return dx*dx + dy*dy
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return distance(positions[i]) < distance(positions[j])
+	})
+	return positions[:min(len(positions), int(count))]
+}
+
+// landStrikes counts the strikes down and hits the standing players within
+// the ones whose warning ran out, returning the strikes landed and the players
+// damaged.
+func (w *World) landStrikes() ([]uint, []uint) {
+	var struck []uint
+	var damaged []uint
+
+	w.Strikes.Each(func(id EntityID, strike *Strike) {
+		if strike.Ticks > 1 {
+			strike.Ticks--
+			return
+		}
+		transform, _ := w.Transforms.Get(id)
+		struck = append(struck, id)
+
+		w.Players.Each(func(player EntityID, profile *Player) {
+			target, ok := w.Transforms.Get(player)
+			if !ok || w.IsDowned(player) {
+				return
+			}
+			dx := float64(target.Position[0]) - float64(transform.Position[0])
+			dy := float64(target.Position[1]) - float64(transform.Position[1])
+			if dx*dx+dy*dy <= strike.Radius*strike.Radius && w.damage(player, w.damageTaken(player, strike.Damage)) {
+				damaged = append(damaged, profile.ID)
+			}
+		})
+	})
+
+	for _, id := range struck {
+		w.RemoveEntity(id)
+	}
+	return struck, damaged
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// chunk is a loaded square of an infinite world.
+type chunk struct {
+	layout   definitions.Layout
+	entities []EntityID // obstacles and terrain zones of the layout
+}
+
+// StartInfiniteMap makes the world infinite, its chunks being generated from
+// the seed as players come near them. The caller must hold the world lock.
+func (w *World) StartInfiniteMap(seed uint64, tuning definitions.MapTuning) {
+	w.infinite = true
+	w.mapTuning = tuning
+	w.chunks = make(map[[2]int]*chunk)
+	w.layout = MapLayout{
+		Seed:           seed,
+		Infinite:       true,
+		ChunkSize:      tuning.ChunkSize,
+		DamageInterval: tuning.DamageInterval,
+	}
+}
+
+// GenerateChunk places the obstacles and terrain zones of a chunk of an
+// infinite world, the same seed, coordinates and tuning always giving the
+// same chunk.
+func GenerateChunk(seed uint64, coords [2]int, tuning definitions.MapTuning) definitions.Layout {
+	stream := uint64(uint32(coords[0]))<<32 | uint64(uint32(coords[1]))
+	random := rand.New(rand.NewPCG(seed, stream))
+
+	size := int(tuning.ChunkSize)
+	origin := [2]int{coords[0] * size, coords[1] * size}
+	return generateLayout(random, origin, size, 0, tuning)
+}
+
+// chunkOf returns the coordinates of the chunk holding the position.
+func (w *World) chunkOf(position [2]int) [2]int {
+	size := int(w.mapTuning.ChunkSize)
+	var coords [2]int
+	for axis := range 2 {
+		coords[axis] = position[axis] / size
+		if position[axis]%size < 0 {
+			coords[axis]--
+		}
+	}
+	return coords
+}
+
+// loadChunksAround loads the chunks within the load radius of the position
+// and returns the ones that were not loaded yet.
+func (w *World) loadChunksAround(position [2]int) []MapChunk {
+	if !w.infinite {
+		return nil
+	}
+
+	center := w.chunkOf(position)
+	radius := int(w.mapTuning.LoadRadius)
+	var loaded []MapChunk
+	for x := center[0] - radius; x <= center[0]+radius; x++ {
+		for y := center[1] - radius; y <= center[1]+radius; y++ {
+			coords := [2]int{x, y}
+			if _, ok := w.chunks[coords]; ok {
+				continue
+			}
+
+			layout := GenerateChunk(w.layout.Seed, coords, w.mapTuning)
+			c := &chunk{layout: layout}
+			for _, obstacle := range layout.Obstacles {
+				c.entities = append(c.entities, w.SpawnObstacle(obstacle.Position, obstacle.Size))
+			}
+			for _, zone := range layout.Zones {
+				c.entities = append(c.entities, w.spawnZone(zone))
+			}
+			w.chunks[coords] = c
+			loaded = append(loaded, MapChunk{Chunk: coords, Layout: layout})
+		}
+	}
+	return loaded
+}
+
+// chunkDistance returns how many chunks apart two chunks are, diagonals
+// counting as one.
+func chunkDistance(a, b [2]int) int {
+	distance := 0
+	for axis := range 2 {
+		distance = max(distance, a[axis]-b[axis], b[axis]-a[axis])
+	}
+	return distance
+}
+
+// loadedChunks returns every loaded chunk, sorted by coordinates.
+func (w *World) loadedChunks() []MapChunk {
+	chunks := make([]MapChunk, 0, len(w.chunks))
+	for coords, c := range w.chunks {
+		chunks = append(chunks, MapChunk{Chunk: coords, Layout: c.layout})
+	}
+	slices.SortFunc(chunks, func(a, b MapChunk) int {
+		return cmp.Or(cmp.Compare(a.Chunk[0], b.Chunk[0]), cmp.Compare(a.Chunk[1], b.Chunk[1]))
+	})
+	return chunks
+}
+
+// updateChunks loads the chunks around every player and unloads the ones one
+// chunk past the load radius of all of them, so that players walking along a
+// chunk border do not load and unload it every tick. Enemies and pickups left
+// in unloaded chunks are removed with them, bosses are kept.
+func updateChunks(w *World, tick uint64) {
+	if !w.infinite {
+		return
+	}
+
+	var loaded []MapChunk
+	var centers [][2]int
+	w.Players.Each(func(id EntityID, _ *Player) {
+		transform, _ := w.Transforms.Get(id)
+		loaded = append(loaded, w.loadChunksAround(transform.Position)...)
+		centers = append(centers, w.chunkOf(transform.Position))
+	})
+
+	keep := int(w.mapTuning.LoadRadius) + 1
+	unloaded := make(map[[2]int]bool)
+	for coords := range w.chunks {
+		far := !slices.ContainsFunc(centers, func(center [2]int) bool {
+			return chunkDistance(coords, center) <= keep
+		})
+		if far {
+			unloaded[coords] = true
+		}
+	}
+
+	if len(loaded) != 0 {
+		w.Emit(ServerMapChunksLoad, loaded)
+	}
+	if len(unloaded) == 0 {
+		return
+	}
+
+	var enemies, pickups []EntityID
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		transform, _ := w.Transforms.Get(id)
+		if !w.Bosses.Has(id) && unloaded[w.chunkOf(transform.Position)] {
+			enemies = append(enemies, id)
+		}
+	})
+	w.Collectibles.Each(func(id EntityID, _ *Collectible) {
+		transform, _ := w.Transforms.Get(id)
+		if unloaded[w.chunkOf(transform.Position)] {
+			pickups = append(pickups, id)
+		}
+	})
+
+	coords := make([][2]int, 0, len(unloaded))
+	for c := range unloaded {
+		for _, id := range w.chunks[c].entities {
+			w.RemoveEntity(id)
+		}
+		delete(w.chunks, c)
+		coords = append(coords, c)
+	}
+	w.invalidateFlowFields()
+	for _, id := range append(enemies, pickups...) {
+		w.RemoveEntity(id)
+	}
+	slices.SortFunc(coords, func(a, b [2]int) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+
+	w.Emit(ServerMapChunksUnload, coords)
+	if len(enemies) != 0 {
+		w.Emit(ServerEnemiesDespawn, enemies)
+	}
+	if len(pickups) != 0 {
+		w.Emit(ServerPickupsDespawn, pickups)
+	}
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+var testChunkTuning = definitions.MapTuning{
+	Infinite:       true,
+	ChunkSize:      512,
+	LoadRadius:     1,
+	Obstacles:      8,
+	ObstacleSize:   [2]uint{48, 160},
+	Zones:          3,
+	ZoneSize:       [2]uint{128, 320},
+	Slow:           40,
+	Damage:         5,
+	DamageInterval: 30,
+}
+
+func TestGenerateChunkDeterministic(t *testing.T) {
+	for _, coords := range [][2]int{{0, 0}, {3, -2}, {-1, -1}, {-1000, 7}} {
+		first := GenerateChunk(42, coords, testChunkTuning)
+		if len(first.Obstacles) == 0 {
+			t.Fatalf("chunk %v has no obstacles", coords)
+		}
+		if second := GenerateChunk(42, coords, testChunkTuning); !reflect.DeepEqual(first, second) {
+			t.Errorf("chunk %v differs between two generations", coords)
+		}
+	}
+
+	base := GenerateChunk(42, [2]int{0, 0}, testChunkTuning)
+	if reflect.DeepEqual(base, GenerateChunk(43, [2]int{0, 0}, testChunkTuning)) {
+		t.Error("another seed gave the same chunk")
+	}
+	if reflect.DeepEqual(base, GenerateChunk(42, [2]int{0, 1}, testChunkTuning)) {
+		t.Error("another chunk of the seed gave the same content")
+	}
+}
+
+func TestGenerateChunkInBounds(t *testing.T) {
+	size := int(testChunkTuning.ChunkSize)
+	for _, coords := range [][2]int{{0, 0}, {2, 5}, {-1, -1}, {-4, 3}} {
+		layout := GenerateChunk(7, coords, testChunkTuning)
+		areas := append([]definitions.Area{}, layout.Obstacles...)
+		for _, zone := range layout.Zones {
+			areas = append(areas, zone.Area)
+		}
+
+		for _, area := range areas {
+			box := newAABB(area.Position, area.Size)
+			for axis := range 2 {
+				low, high := coords[axis]*size, (coords[axis]+1)*size
+				if box.Min[axis] < low || box.Max[axis] > high {
+					t.Errorf("chunk %v has an area %v outside of it", coords, box)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkOf(t *testing.T) {
+	w := NewWorld()
+	w.StartInfiniteMap(1, testChunkTuning)
+	size := int(testChunkTuning.ChunkSize)
+
+	tests := []struct {
+		position, want [2]int
+	}{
+		{[2]int{0, 0}, [2]int{0, 0}},
+		{[2]int{size - 1, size}, [2]int{0, 1}},
+		{[2]int{-1, 0}, [2]int{-1, 0}},
+		{[2]int{-size, -1}, [2]int{-1, -1}},
+		{[2]int{-size - 1, 3 * size}, [2]int{-2, 3}},
+	}
+
+	for _, test := range tests {
+		if got := w.chunkOf(test.position); got != test.want {
+			t.Errorf("chunkOf(%v) = %v, want %v", test.position, got, test.want)
+		}
+	}
+}
+
+func TestLoadChunksAround(t *testing.T) {
+	w := NewWorld()
+	w.StartInfiniteMap(1, testChunkTuning)
+
+	loaded := w.loadChunksAround([2]int{-1, -1})
+	if len(loaded) != 9 {
+		t.Fatalf("loaded %d chunks, want 9", len(loaded))
+	}
+	if _, ok := w.chunks[[2]int{-2, -2}]; !ok {
+		t.Error("chunk -2, -2 around -1, -1 was not loaded")
+	}
+	for _, chunk := range loaded {
+		if !reflect.DeepEqual(chunk.Layout, GenerateChunk(1, chunk.Chunk, testChunkTuning)) {
+			t.Errorf("loaded chunk %v differs from its generation", chunk.Chunk)
+		}
+	}
+
+	if again := w.loadChunksAround([2]int{-1, -1}); len(again) != 0 {
+		t.Errorf("loaded %d chunks again", len(again))
+	}
+	if next := w.loadChunksAround([2]int{0, -1}); len(next) != 3 {
+		t.Errorf("loaded %d chunks one chunk further, want 3", len(next))
+	}
+}
@@ -0,0 +1,336 @@
+package game
+
+// gridCellSize is the size of the spatial hash cells, larger than any moving
+// square so most queries only look at a few cells.
+const gridCellSize = 64
+
+// contactMargin is how close two squares must be to be touching.
+const contactMargin = 1
+
+// Contact is a pair of entities touching each other after movement, Entity
+// being the one with an AI.
+type Contact struct {
+	Entity EntityID
+	Other  EntityID
+}
+
+// aabb is an axis aligned box, Min inclusive and Max exclusive.
+type aabb struct {
+	Min [2]int
+	Max [2]int
+}
+
+func newAABB(center [2]int, size uint) aabb {
+	var box aabb
+	for axis := range 2 {
+		box.Min[axis] = center[axis] - int(size/2)
+		box.Max[axis] = box.Min[axis] + int(size)
+	}
+	return box
+}
+
+func (a aabb) overlaps(b aabb) bool {
+	return a.Min[0] < b.Max[0] && b.Min[0] < a.Max[0] && a.Min[1] < b.Max[1] && b.Min[1] < a.Max[1]
+}
+
+func (a aabb) grow(margin int) aabb {
+	return aabb{
+		Min: [2]int{a.Min[0] - margin, a.Min[1] - margin},
+		Max: [2]int{a.Max[0] + margin, a.Max[1] + margin},
+	}
+}
+
+// penetration returns how deep a and b overlap along each axis.
+func (a aabb) penetration(b aabb) [2]int {
+	var p [2]int
+	for axis := range 2 {
+		p[axis] = min(a.Max[axis], b.Max[axis]) - max(a.Min[axis], b.Min[axis])
+	}
+	return p
+}
+
+// spatialHash buckets entities by the grid cells their box covers, it is the
+// broad phase narrowing collision checks down to nearby entities.
+type spatialHash struct {
+	cells map[[2]int][]EntityID
+}
+
+func newSpatialHash() *spatialHash {
+	return &spatialHash{cells: make(map[[2]int][]EntityID)}
+}
+
+func cellRange(box aabb) (from [2]int, to [2]int) {
+	for axis := range 2 {
+		from[axis] = floorDiv(box.Min[axis], gridCellSize)
+		to[axis] = floorDiv(box.Max[axis]-1, gridCellSize)
+	}
+	return from, to
+}
+
+func floorDiv(a int, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+func (h *spatialHash) insert(id EntityID, box aabb) {
+	from, to := cellRange(box)
+	for x := from[0]; x <= to[0]; x++ {
+		for y := from[1]; y <= to[1]; y++ {
+			cell := [2]int{x, y}
+			h.cells[cell] = append(h.cells[cell], id)
+		}
+	}
+}
+
+func (h *spatialHash) remove(id EntityID, box aabb) {
+	from, to := cellRange(box)
+	for x := from[0]; x <= to[0]; x++ {
+		for y := from[1]; y <= to[1]; y++ {
+			cell := [2]int{x, y}
+			ids := h.cells[cell]
+			for i, other := range ids {
+				if other == id {
+					ids[i] = ids[len(ids)-1]
+					h.cells[cell] = ids[:len(ids)-1]
+					break
+				}
+			}
+		}
+	}
+}
+
+func (h *spatialHash) move(id EntityID, from aabb, to aabb) {
+	h.remove(id, from)
+	h.insert(id, to)
+}
+
+// query returns the entities whose cells intersect the box, each once.
+func (h *spatialHash) query(box aabb) []EntityID {
+	var found []EntityID
+	seen := make(map[EntityID]struct{})
+
+	from, to := cellRange(box)
+	for x := from[0]; x <= to[0]; x++ {
+		for y := from[1]; y <= to[1]; y++ {
+			for _, id := range h.cells[[2]int{x, y}] {
+				if _, ok := seen[id]; !ok {
+					seen[id] = struct{}{}
+					found = append(found, id)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// box returns the current box of an entity with a Transform and a Collider.
+func (w *World) box(id EntityID) aabb {
+	transform, _ := w.Transforms.Get(id)
+	collider, _ := w.Colliders.Get(id)
+	return newAABB(transform.Position, collider.Size)
+}
+
+func (w *World) buildSpatialHash() *spatialHash {
+	hash := newSpatialHash()
+	w.Colliders.Each(func(id EntityID, _ *Collider) {
+		if w.Transforms.Has(id) {
+			hash.insert(id, w.box(id))
+		}
+	})
+	return hash
+}
+
+// blocks reports whether other stops mover from walking into it. Static
+// colliders block everything, players block each other and enemies, while
+// players walk through enemies and enemies are pushed apart by separation.
+func (w *World) blocks(mover EntityID, other EntityID) bool {
+	collider, _ := w.Colliders.Get(other)
+	if collider.Static {
+		return true
+	}
+	return w.Players.Has(other) && (w.Players.Has(mover) || w.AIs.Has(mover))
+}
+
+// clampToWorld keeps a square of the given size inside the bounds of a
+// bounded world.
+func clampToWorld(position int, size uint) int {
+	return clampToRegion(position, size, WorldSize)
+}
+
+// clampToRegion keeps a square of the given size inside a region of the
+// region size starting at 0.
+func clampToRegion(position int, size uint, region int) int {
+	low := int(size / 2)
+	high := region - int(size-size/2)
+	return max(low, min(position, high))
+}
+
+// clamp keeps a square of the given size inside the world, an infinite world
+// having no bounds.
+func (w *World) clamp(position int, size uint) int {
+	if w.infinite {
+		return position
+	}
+	return clampToWorld(position, size)
+}
+
+// moveAxis moves the entity along one axis as far as the blockers allow.
+func (w *World) moveAxis(hash *spatialHash, id EntityID, axis int, delta int) {
+	if delta == 0 {
+		return
+	}
+
+	transform, _ := w.Transforms.Get(id)
+	collider, _ := w.Colliders.Get(id)
+	from := newAABB(transform.Position, collider.Size)
+
+	target := w.clamp(transform.Position[axis]+delta, collider.Size)
+	position := transform.Position
+	position[axis] = target
+	to := newAABB(position, collider.Size)
+
+	for _, other := range hash.query(to) {
+		if other == id || !w.blocks(id, other) {
+			continue
+		}
+
+		otherBox := w.box(other)
+		// Entities already overlapping, like one spawned inside the other,
+		// may move apart freely
+		if !to.overlaps(otherBox) || from.overlaps(otherBox) {
+			continue
+		}
+
+		if delta > 0 {
+			target = min(target, otherBox.Min[axis]-int(collider.Size-collider.Size/2))
+		} else {
+			target = max(target, otherBox.Max[axis]+int(collider.Size/2))
+		}
+	}
+
+	position[axis] = target
+	to = newAABB(position, collider.Size)
+	hash.move(id, from, to)
+	transform.Position = position
+}
+
+// updateMovement moves the entities with a Motion, resolves collisions between
+// them and records the contacts for the systems after it.
+func updateMovement(w *World, tick uint64) {
+	hash := w.buildSpatialHash()
+	moved := make(map[EntityID]struct{})
+
+	w.Motions.Each(func(id EntityID, motion *Motion) {
+		if !w.Transforms.Has(id) || !w.Colliders.Has(id) || w.IsDowned(id) {
+			motion.Direction = [2]int{}
+			return
+		}
+
+		transform, _ := w.Transforms.Get(id)
+		start := transform.Position
+		// Fractions of a unit are carried over, so that small speed bonuses
+		// and slows still add up on slow entities.
+		var speed int
+		if motion.Direction != ([2]int{}) {
+			distance := w.moveSpeed(id, motion) + motion.Carry
+			speed = int(distance)
+			motion.Carry = distance - float64(speed)
+		}
+		for axis := range 2 {
+			w.moveAxis(hash, id, axis, motion.Direction[axis]*speed)
+		}
+		motion.Direction = [2]int{}
+
+		if transform.Position != start {
+			moved[id] = struct{}{}
+		}
+	})
+
+	w.separateEnemies(hash, moved)
+	w.findContacts(hash)
+	w.emitMoves(moved)
+}
+
+// separateEnemies pushes overlapping enemies apart along the axis they overlap
+// the least, so crowds spread out instead of stacking on the same square.
+func (w *World) separateEnemies(hash *spatialHash, moved map[EntityID]struct{}) {
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		if !w.Colliders.Has(id) {
+			return
+		}
+
+		for _, other := range hash.query(w.box(id)) {
+			if other <= id || !w.AIs.Has(other) {
+				continue
+			}
+
+			box, otherBox := w.box(id), w.box(other)
+			if !box.overlaps(otherBox) {
+				continue
+			}
+
+			penetration := box.penetration(otherBox)
+			axis := 0
+			if penetration[1] < penetration[0] {
+				axis = 1
+			}
+
+			direction := 1
+			transform, _ := w.Transforms.Get(id)
+			otherTransform, _ := w.Transforms.Get(other)
+			if transform.Position[axis] < otherTransform.Position[axis] ||
+				(transform.Position[axis] == otherTransform.Position[axis] && id < other) {
+				direction = -1
+			}
+
+			push := (penetration[axis] + 1) / 2
+			w.moveAxis(hash, id, axis, direction*push)
+			w.moveAxis(hash, other, axis, -direction*push)
+			moved[id] = struct{}{}
+			moved[other] = struct{}{}
+		}
+	})
+}
+
+// findContacts records every enemy touching a player.
+func (w *World) findContacts(hash *spatialHash) {
+	w.contacts = w.contacts[:0]
+
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		if !w.Colliders.Has(id) {
+			return
+		}
+
+		box := w.box(id).grow(contactMargin)
+		for _, other := range hash.query(box) {
+			if w.Players.Has(other) && !w.IsDowned(other) && box.overlaps(w.box(other)) {
+				w.contacts = append(w.contacts, Contact{Entity: id, Other: other})
+			}
+		}
+	})
+}
+
+func (w *World) emitMoves(moved map[EntityID]struct{}) {
+	var players, enemies []MovedEntity
+	for id := range moved {
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			continue
+		}
+
+		if player, ok := w.Players.Get(id); ok {
+			players = append(players, MovedEntity{ID: player.ID, Position: transform.Position})
+		} else if w.AIs.Has(id) {
+			enemies = append(enemies, MovedEntity{ID: id, Position: transform.Position})
+		}
+	}
+
+	if len(players) != 0 {
+		w.Emit(ServerMovePlayers, players)
+	}
+	if len(enemies) != 0 {
+		w.Emit(ServerMoveEnemies, enemies)
+	}
+}
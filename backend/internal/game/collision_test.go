@@ -0,0 +1,229 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAABBOverlaps(t *testing.T) {
+	box := aabb{Min: [2]int{0, 0}, Max: [2]int{10, 10}}
+
+	tests := []struct {
+		name  string
+		other aabb
+		want  bool
+	}{
+		{"same box", box, true},
+		{"inside", aabb{Min: [2]int{2, 2}, Max: [2]int{4, 4}}, true},
+		{"corner overlap", aabb{Min: [2]int{9, 9}, Max: [2]int{12, 12}}, true},
+		{"touching right edge", aabb{Min: [2]int{10, 0}, Max: [2]int{20, 10}}, false},
+		{"touching bottom edge", aabb{Min: [2]int{0, 10}, Max: [2]int{10, 20}}, false},
+		{"touching left edge", aabb{Min: [2]int{-10, 0}, Max: [2]int{0, 10}}, false},
+		{"overlapping on one axis only", aabb{Min: [2]int{5, 11}, Max: [2]int{15, 20}}, false},
+		{"negative overlap", aabb{Min: [2]int{-5, -5}, Max: [2]int{1, 1}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := box.overlaps(test.other); got != test.want {
+				t.Errorf("overlaps = %v, want %v", got, test.want)
+			}
+			if got := test.other.overlaps(box); got != test.want {
+				t.Errorf("overlaps is not symmetric")
+			}
+		})
+	}
+}
+
+func TestAABBGrow(t *testing.T) {
+	box := aabb{Min: [2]int{0, 0}, Max: [2]int{10, 10}}
+	right := aabb{Min: [2]int{10, 0}, Max: [2]int{20, 10}}
+	apart := aabb{Min: [2]int{12, 0}, Max: [2]int{20, 10}}
+
+	tests := []struct {
+		name   string
+		margin int
+		other  aabb
+		want   bool
+	}{
+		{"touching needs a margin", 0, right, false},
+		{"margin makes touching overlap", contactMargin, right, true},
+		{"margin too small for a gap", 1, apart, false},
+		{"margin reaching past a gap", 3, apart, true},
+		{"negative margin shrinks", -1, aabb{Min: [2]int{9, 9}, Max: [2]int{12, 12}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := box.grow(test.margin).overlaps(test.other); got != test.want {
+				t.Errorf("grown overlaps = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if grown := newAABB([2]int{0, 0}, 4).grow(2); grown != (aabb{Min: [2]int{-4, -4}, Max: [2]int{4, 4}}) {
+		t.Errorf("grow = %v", grown)
+	}
+}
+
+func TestNewAABB(t *testing.T) {
+	tests := []struct {
+		center [2]int
+		size   uint
+		want   aabb
+	}{
+		{[2]int{10, 10}, 4, aabb{Min: [2]int{8, 8}, Max: [2]int{12, 12}}},
+		{[2]int{10, 10}, 5, aabb{Min: [2]int{8, 8}, Max: [2]int{13, 13}}},
+		{[2]int{-3, 0}, 2, aabb{Min: [2]int{-4, -1}, Max: [2]int{-2, 1}}},
+	}
+
+	for _, test := range tests {
+		if got := newAABB(test.center, test.size); got != test.want {
+			t.Errorf("newAABB(%v, %d) = %v, want %v", test.center, test.size, got, test.want)
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct{ a, b, want int }{
+		{0, 64, 0},
+		{63, 64, 0},
+		{64, 64, 1},
+		{-1, 64, -1},
+		{-64, 64, -1},
+		{-65, 64, -2},
+		{-128, 64, -2},
+	}
+
+	for _, test := range tests {
+		if got := floorDiv(test.a, test.b); got != test.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestCellRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      aabb
+		from, to [2]int
+	}{
+		{"inside one cell", aabb{Min: [2]int{1, 1}, Max: [2]int{10, 10}}, [2]int{0, 0}, [2]int{0, 0}},
+		{"max is exclusive", aabb{Min: [2]int{0, 0}, Max: [2]int{gridCellSize, gridCellSize}}, [2]int{0, 0}, [2]int{0, 0}},
+		{"crossing a border", aabb{Min: [2]int{60, 0}, Max: [2]int{70, 10}}, [2]int{0, 0}, [2]int{1, 0}},
+		{"negative cell", aabb{Min: [2]int{-10, -10}, Max: [2]int{-1, -1}}, [2]int{-1, -1}, [2]int{-1, -1}},
+		{"crossing the origin", aabb{Min: [2]int{-5, -70}, Max: [2]int{5, 0}}, [2]int{-1, -2}, [2]int{0, -1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to := cellRange(test.box)
+			if from != test.from || to != test.to {
+				t.Errorf("cellRange = %v, %v, want %v, %v", from, to, test.from, test.to)
+			}
+		})
+	}
+}
+
+func TestSpatialHash(t *testing.T) {
+	hash := newSpatialHash()
+	boxes := map[EntityID]aabb{
+		1: newAABB([2]int{-10, -10}, 8),  // cell -1, -1
+		2: newAABB([2]int{0, 0}, 8),      // four cells around the origin
+		3: newAABB([2]int{100, 100}, 8),  // cell 1, 1
+		4: newAABB([2]int{-100, 30}, 8),  // cell -2, 0
+		5: newAABB([2]int{-70, -70}, 20), // four cells around -64, -64
+	}
+	for id, box := range boxes {
+		hash.insert(id, box)
+	}
+
+	tests := []struct {
+		name string
+		box  aabb
+		want []EntityID
+	}{
+		{"negative cell", newAABB([2]int{-30, -30}, 4), []EntityID{1, 2, 5}},
+		{"positive cell", newAABB([2]int{120, 120}, 4), []EntityID{3}},
+		{"left of the origin", newAABB([2]int{-120, 10}, 4), []EntityID{4}},
+		{"corner of four cells", newAABB([2]int{-64, -64}, 2), []EntityID{1, 2, 5}},
+		{"empty cell", newAABB([2]int{500, -500}, 4), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hash.query(test.box)
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("query = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	hash.move(1, boxes[1], newAABB([2]int{120, 120}, 8))
+	got := hash.query(newAABB([2]int{-30, -30}, 4))
+	slices.Sort(got)
+	if !slices.Equal(got, []EntityID{2, 5}) {
+		t.Errorf("query after moving out = %v, want [2 5]", got)
+	}
+	got = hash.query(newAABB([2]int{120, 120}, 4))
+	slices.Sort(got)
+	if !slices.Equal(got, []EntityID{1, 3}) {
+		t.Errorf("query after moving in = %v, want [1 3]", got)
+	}
+}
+
+func TestMoveAxisAgainstStatic(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    [2]int
+		size     uint
+		obstacle [2]int
+		axis     int
+		delta    int
+		want     [2]int
+	}{
+		{"free move", [2]int{100, 100}, 10, [2]int{300, 300}, 0, 20, [2]int{120, 100}},
+		{"stops flush moving right", [2]int{100, 100}, 10, [2]int{130, 100}, 0, 20, [2]int{115, 100}},
+		{"stops flush moving left", [2]int{100, 100}, 10, [2]int{70, 100}, 0, -20, [2]int{85, 100}},
+		{"stops flush moving down", [2]int{100, 100}, 10, [2]int{100, 130}, 1, 20, [2]int{100, 115}},
+		{"stops flush moving up", [2]int{100, 100}, 10, [2]int{100, 70}, 1, -20, [2]int{100, 85}},
+		{"odd size stops flush", [2]int{100, 100}, 11, [2]int{130, 100}, 0, 20, [2]int{114, 100}},
+		{"already flush stays", [2]int{115, 100}, 10, [2]int{130, 100}, 0, 5, [2]int{115, 100}},
+		{"slides past on the other axis", [2]int{100, 100}, 10, [2]int{130, 120}, 0, 20, [2]int{120, 100}},
+		{"overlapping moves apart", [2]int{125, 100}, 10, [2]int{130, 100}, 0, -20, [2]int{105, 100}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWorld()
+			id := w.NewEntity()
+			w.Transforms.Add(id, Transform{Position: test.start})
+			w.Colliders.Add(id, Collider{Size: test.size})
+			w.SpawnObstacle(test.obstacle, 20)
+
+			w.moveAxis(w.buildSpatialHash(), id, test.axis, test.delta)
+			if transform, _ := w.Transforms.Get(id); transform.Position != test.want {
+				t.Errorf("position = %v, want %v", transform.Position, test.want)
+			}
+		})
+	}
+}
+
+func TestMoveAxisClampsToWorld(t *testing.T) {
+	w := NewWorld()
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{Position: [2]int{10, 10}})
+	w.Colliders.Add(id, Collider{Size: 10})
+
+	w.moveAxis(w.buildSpatialHash(), id, 0, -50)
+	if transform, _ := w.Transforms.Get(id); transform.Position != [2]int{5, 10} {
+		t.Errorf("bounded position = %v, want [5 10]", transform.Position)
+	}
+
+	w.infinite = true
+	w.moveAxis(w.buildSpatialHash(), id, 0, -50)
+	if transform, _ := w.Transforms.Get(id); transform.Position != [2]int{-45, 10} {
+		t.Errorf("infinite position = %v, want [-45 10]", transform.Position)
+	}
+}
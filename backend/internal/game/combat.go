@@ -0,0 +1,72 @@
+package game
+
+func (weapon *EquippedWeapon) IsMelee() bool {
+	return len(weapon.Projectile) == 0
+}
+
+// ready reports whether the weapon can hit and if so starts a cooldown of the
+// given ticks.
+func (weapon *EquippedWeapon) ready(cooldown uint) bool {
+	if weapon.Cooldown != 0 {
+		return false
+	}
+	weapon.Cooldown = cooldown
+	return true
+}
+
+// damage lowers the entity's HP without going below zero and returns whether
+// it took any damage.
+func (w *World) damage(id EntityID, amount uint) bool {
+	health, ok := w.Healths.Get(id)
+	if !ok || health.HP == 0 || amount == 0 {
+		return false
+	}
+	health.HP -= min(health.HP, amount)
+	return true
+}
+
+// hit damages the entity on behalf of the player, who is credited if it dies.
+func (w *World) hit(playerID uint, id EntityID, amount uint) bool {
+	if !w.damage(id, amount) {
+		return false
+	}
+	health, _ := w.Healths.Get(id)
+	health.LastHitBy = playerID
+	return true
+}
+
+// updateCombat cools the weapons down and lets melee enemies hit the players
+// they touch.
+func updateCombat(w *World, tick uint64) {
+	w.Weapons.Each(func(id EntityID, armament *Armament) {
+		for i := range armament.Weapons {
+			if armament.Weapons[i].Cooldown != 0 {
+				armament.Weapons[i].Cooldown--
+			}
+		}
+	})
+
+	var damagedPlayers []uint
+	for _, contact := range w.contacts {
+		armament, ok := w.Weapons.Get(contact.Entity)
+		if !ok {
+			continue
+		}
+
+		for i := range armament.Weapons {
+			weapon := &armament.Weapons[i]
+			if !weapon.IsMelee() || !weapon.ready(w.weaponCooldown(contact.Entity, weapon)) {
+				continue
+			}
+
+			if w.damage(contact.Other, w.damageTaken(contact.Other, w.weaponDamage(contact.Entity, weapon))) {
+				player, _ := w.Players.Get(contact.Other)
+				damagedPlayers = append(damagedPlayers, player.ID)
+			}
+		}
+	}
+
+	if len(damagedPlayers) != 0 {
+		w.Emit(ServerDamagePlayers, damagedPlayers)
+	}
+}
@@ -0,0 +1,148 @@
+package game
+
+import "valley-of-survival-dawn-of-squares/internal/definitions"
+
+// Transform places an entity in the world, Position being its center.
+type Transform struct {
+	Position [2]int
+}
+
+type Health struct {
+	HP        uint
+	MaxHP     uint
+	LastHitBy uint // ID of the player who last damaged it, 0 if none
+}
+
+// EquippedWeapon is a weapon in use by an entity, Cooldown counts the ticks
+// left until it can hit again.
+type EquippedWeapon struct {
+	WeaponID      uint // ID of the weapon in the database, 0 until it is saved
+	WeaponClassID uint
+	EvolvedFrom   uint // weapon class it evolved from since it was loaded, 0 if none
+	Level         uint
+	Damage        uint
+	Range         float32 // player sizes
+	CooldownTicks uint
+	Cooldown      uint
+	Projectile    string  // key of the projectile definition, empty for melee weapons
+	AoE           float32 // player sizes
+}
+
+// NewEquippedWeapon returns the weapon of the definition at the given level.
+func NewEquippedWeapon(definition *definitions.Weapon, level uint) EquippedWeapon {
+	return EquippedWeapon{
+		WeaponClassID: definition.ID,
+		Level:         level,
+		Damage:        definition.DamageAt(level),
+		Range:         definition.Range,
+		CooldownTicks: definition.Cooldown,
+		Projectile:    definition.Projectile,
+		AoE:           definition.AoE,
+	}
+}
+
+// Armament holds the weapons of an entity.
+type Armament struct {
+	Weapons []EquippedWeapon
+}
+
+const (
+	AIChase = "chase" // walks straight to the closest player
+)
+
+type AI struct {
+	Kind      string
+	Archetype string   // key of the enemy definition
+	Target    EntityID // 0 when there is none
+	HP        uint     // percent of the archetype's HP, from the run mode of the player it spawned around
+}
+
+// Collider makes an entity an axis aligned square of the given size, static
+// colliders are never moved by collisions.
+type Collider struct {
+	Size   uint
+	Static bool
+}
+
+// Motion moves an entity by Speed world units per tick along Direction, whose
+// coordinates are -1, 0 or 1. Direction is reset after every move.
+type Motion struct {
+	Direction [2]int
+	Speed     uint
+	Carry     float64 // fraction of a world unit moved but not applied yet
+}
+
+// Controller lets the client of the session steer the entity.
+type Controller struct {
+	SessionID string
+}
+
+// Owner links an entity to the player it belongs to, such as the player's own
+// entity or the things they fire.
+type Owner struct {
+	PlayerID uint
+}
+
+// Collectible is the component of pickups lying in the world, collected by the
+// first player touching them. The exact position is kept as floats while a
+// magnet pulls the pickup towards Target.
+type Collectible struct {
+	Kind     string // kind of loot
+	Amount   uint
+	Duration uint // ticks, for buffs
+	Lifetime uint // ticks left before it despawns, paused while pulled
+	Position [2]float64
+	Target   EntityID // player pulling it, 0 when lying still
+}
+
+// Buff is a temporary bonus of Amount percent to the stat of its kind.
+type Buff struct {
+	Kind   string
+	Amount uint
+	Ticks  uint // ticks left
+}
+
+// Buffs holds the active buffs of an entity.
+type Buffs struct {
+	Active []Buff
+}
+
+// Downed marks a player whose HP reached zero. Allies standing nearby revive
+// them, otherwise they die for good once BleedOut runs out.
+type Downed struct {
+	BleedOut uint // ticks left
+	Progress uint // ticks an ally has been reviving them
+}
+
+// Run tracks a player's run from spawning to their final death.
+type Run struct {
+	StartTick  uint64
+	StartEXP   uint
+	Kills      uint
+	BossMinute uint // minutes into the run the bosses were spawned up to
+	Mode       RunMode
+}
+
+// Terrain is a zone of the map slowing down or hurting the entities standing
+// in it.
+type Terrain struct {
+	Kind   string // kind of zone
+	Amount uint
+	Size   uint
+}
+
+// Boss drives the phases and area attacks of a boss enemy.
+type Boss struct {
+	Phase    int  // index of the phase in the definition
+	Cooldown uint // ticks left before the next attack
+	HP       uint // last HP sent to clients
+}
+
+// Strike is a telegraphed area attack of a boss, hitting the players within
+// Radius of its position once its Ticks run out.
+type Strike struct {
+	Source EntityID // the boss
+	Radius float64  // world units
+	Damage uint
+	Ticks  uint // ticks left
+}
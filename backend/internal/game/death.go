@@ -0,0 +1,219 @@
+package game
+
+import (
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// PlayerDeath is the final death of a player, handed to the OnPlayerDeath
+// handlers once the tick is over.
+type PlayerDeath struct {
+	SessionID string
+	Snapshot  PlayerSnapshot // last state of the player, HP being zero
+	Summary   RunSummary
+}
+
+var deathHandlers []func(death PlayerDeath)
+
+// OnPlayerDeath registers a handler run after the tick a player died in. It
+// runs in its own goroutine and must be registered before StartWorld.
+func OnPlayerDeath(handler func(death PlayerDeath)) {
+	deathHandlers = append(deathHandlers, handler)
+}
+
+func (w *World) flushDeaths() []PlayerDeath {
+	deaths := w.deaths
+	w.deaths = nil
+	return deaths
+}
+
+// IsDowned reports whether the entity is a downed player.
+func (w *World) IsDowned(id EntityID) bool {
+	return w.Downed.Has(id)
+}
+
+// RespawnCooldown returns how long the player must wait before spawning again
+// after their final death. The caller must hold the world lock.
+func (w *World) RespawnCooldown(playerID uint) time.Duration {
+	at, ok := w.respawnAt[playerID]
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(at); wait > 0 {
+		return wait
+	}
+	delete(w.respawnAt, playerID)
+	return 0
+}
+
+// startRespawnCooldown keeps the player from spawning again for the cooldown
+// of the tuning.
+func (w *World) startRespawnCooldown(playerID uint) {
+	cooldown := definitions.Current().Tuning.Revive.RespawnCooldown
+	w.respawnAt[playerID] = time.Now().Add(time.Duration(cooldown) * TickInterval())
+}
+
+// allies reports whether two players fight on the same side, which is being
+// in the same clan or the same party.
+func (w *World) allies(a EntityID, b EntityID) bool {
+	first, ok := w.Players.Get(a)
+	if !ok {
+		return false
+	}
+	second, ok := w.Players.Get(b)
+	if !ok {
+		return false
+	}
+	if party := w.parties[first.ID]; party != 0 && party == w.parties[second.ID] {
+		return true
+	}
+	return first.ClanID != nil && second.ClanID != nil && *first.ClanID == *second.ClanID
+}
+
+// reviverNearby reports whether an ally who is not downed stands within radius
+// of the downed player.
+func (w *World) reviverNearby(id EntityID, radius uint) bool {
+	transform, ok := w.Transforms.Get(id)
+	if !ok {
+		return false
+	}
+
+	found := false
+	w.Players.Each(func(other EntityID, _ *Player) {
+		if found || other == id || w.IsDowned(other) || !w.allies(id, other) {
+			return
+		}
+		otherTransform, ok := w.Transforms.Get(other)
+		if !ok {
+			return
+		}
+
+		dx := otherTransform.Position[0] - transform.Position[0]
+		dy := otherTransform.Position[1] - transform.Position[1]
+		found = dx*dx+dy*dy <= int(radius*radius)
+	})
+	return found
+}
+
+// updateRevive downs the players whose HP reached zero, revives the downed
+// players allies stayed next to long enough and kills the ones who bled out.
+func updateRevive(w *World, tick uint64) {
+	tuning := definitions.Current().Tuning.Revive
+
+	var downed []DownedPlayer
+	var revived []uint
+	var dead []EntityID
+
+	w.Players.Each(func(id EntityID, player *Player) {
+		health, ok := w.Healths.Get(id)
+		if !ok {
+			return
+		}
+
+		state, ok := w.Downed.Get(id)
+		if !ok {
+			if health.HP == 0 {
+				w.Downed.Add(id, Downed{BleedOut: tuning.BleedOut})
+				downed = append(downed, DownedPlayer{ID: player.ID, BleedOut: tuning.BleedOut})
+			}
+			return
+		}
+
+		if w.reviverNearby(id, tuning.Radius) {
+			state.Progress++
+			if state.Progress >= tuning.Duration {
+				health.HP = max(1, health.MaxHP*tuning.HP/100)
+				w.Downed.Remove(id)
+				revived = append(revived, player.ID)
+			}
+			return
+		}
+
+		state.Progress = 0
+		if state.BleedOut == 0 {
+			dead = append(dead, id)
+			return
+		}
+		state.BleedOut--
+	})
+
+	var summaries []RunSummary
+	for _, id := range dead {
+		summaries = append(summaries, w.die(id, tick))
+	}
+
+	if len(downed) != 0 {
+		w.Emit(ServerPlayersDown, downed)
+	}
+	if len(revived) != 0 {
+		w.Emit(ServerPlayersRevive, revived)
+	}
+	if len(summaries) != 0 {
+		w.Emit(ServerPlayersDeath, summaries)
+	}
+}
+
+// die ends the run of the downed player, removing them from the world.
+func (w *World) die(id EntityID, tick uint64) RunSummary {
+	summary := w.runSummary(id, tick)
+
+	var sessionID string
+	if controller, ok := w.Controls.Get(id); ok {
+		sessionID = controller.SessionID
+	}
+
+	snapshot, _ := w.DespawnPlayer(summary.PlayerID)
+	w.deaths = append(w.deaths, PlayerDeath{SessionID: sessionID, Snapshot: snapshot, Summary: summary})
+	return summary
+}
+
+func (w *World) runSummary(id EntityID, tick uint64) RunSummary {
+	player := w.PlayerState(id)
+	summary := RunSummary{
+		PlayerID: player.ID,
+		Level:    definitions.Current().Tuning.ExpCurve.LevelForExp(player.EXP),
+		Seed:     w.layout.Seed,
+		Layout:   w.layout.Name,
+	}
+
+	if run, ok := w.Runs.Get(id); ok {
+		summary.Ticks = tick - run.StartTick
+		summary.Seconds = (time.Duration(summary.Ticks) * TickInterval()).Seconds()
+		summary.Kills = run.Kills
+		summary.EXP = player.EXP - min(player.EXP, run.StartEXP)
+		summary.RunMode = run.Mode
+	}
+	return summary
+}
+
+// RunMode returns the mode of the run the player entity is playing, false if
+// it is not in one.
+func (w *World) RunMode(id EntityID) (RunMode, bool) {
+	if run, ok := w.Runs.Get(id); ok {
+		return run.Mode, true
+	}
+	return RunMode{}, false
+}
+
+// runModifiers returns how the mode of the player entity's run changes enemies,
+// spawns and rewards, neutral if it is not in one.
+func (w *World) runModifiers(id EntityID) definitions.RunModifiers {
+	mode, ok := w.RunMode(id)
+	if !ok {
+		return definitions.NeutralModifiers
+	}
+	return definitions.Current().RunModifiers(mode.Difficulty, mode.Curses)
+}
+
+// creditKill counts the kill in the run of the player who dealt the last hit.
+func (w *World) creditKill(enemy EntityID) {
+	health, ok := w.Healths.Get(enemy)
+	if !ok || health.LastHitBy == 0 {
+		return
+	}
+	if id, ok := w.playerEntities[health.LastHitBy]; ok {
+		if run, ok := w.Runs.Get(id); ok {
+			run.Kills++
+		}
+	}
+}
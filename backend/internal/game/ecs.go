@@ -0,0 +1,222 @@
+package game
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// EntityID identifies an entity of the world, IDs are never reused while the
+// server runs.
+type EntityID = uint
+
+// Store holds the components of one type, keyed by the entity they belong to.
+type Store[T any] struct {
+	components map[EntityID]*T
+}
+
+func newStore[T any]() Store[T] {
+	return Store[T]{components: make(map[EntityID]*T)}
+}
+
+// Add sets the component of the entity and returns a pointer to the stored copy.
+func (s *Store[T]) Add(id EntityID, component T) *T {
+	stored := &component
+	s.components[id] = stored
+	return stored
+}
+
+func (s *Store[T]) Get(id EntityID) (*T, bool) {
+	component, ok := s.components[id]
+	return component, ok
+}
+
+func (s *Store[T]) Has(id EntityID) bool {
+	_, ok := s.components[id]
+	return ok
+}
+
+func (s *Store[T]) Remove(id EntityID) {
+	delete(s.components, id)
+}
+
+func (s *Store[T]) Len() int {
+	return len(s.components)
+}
+
+// IDs returns the entities having the component in increasing order, so
+// systems iterate deterministically.
+func (s *Store[T]) IDs() []EntityID {
+	ids := make([]EntityID, 0, len(s.components))
+	for id := range s.components {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Each calls f for every entity having the component, in increasing order.
+// Entities f removes are skipped and the ones it adds are not visited.
+func (s *Store[T]) Each(f func(id EntityID, component *T)) {
+	for _, id := range s.IDs() {
+		if component, ok := s.components[id]; ok {
+			f(id, component)
+		}
+	}
+}
+
+// World holds every entity and its components. Systems run with the world
+// locked, anything else must hold the lock while touching it.
+type World struct {
+	sync.RWMutex
+
+	nextID   EntityID
+	entities map[EntityID]struct{}
+	removers []func(id EntityID)
+
+	Transforms   Store[Transform]
+	Healths      Store[Health]
+	Weapons      Store[Armament]
+	AIs          Store[AI]
+	Colliders    Store[Collider]
+	Owners       Store[Owner]
+	Motions      Store[Motion]
+	Controls     Store[Controller]
+	Projectiles  Store[Ballistics]
+	Collectibles Store[Collectible]
+	Buffs        Store[Buffs]
+	Downed       Store[Downed]
+	Runs         Store[Run]
+	Passives     Store[Passives]
+	Modifiers    Store[Modifiers]
+	Progressions Store[Progression]
+	Bosses       Store[Boss]
+	Strikes      Store[Strike]
+	Terrains     Store[Terrain]
+	Players      Store[Player] // profile of the spawned player, position and HP live in Transform and Health
+
+	playerEntities  map[uint]EntityID   // player ID -> entity
+	sessionEntities map[string]EntityID // session ID -> entity of a Controller
+	respawnAt       map[uint]time.Time  // player ID -> when they may spawn again after dying
+	parties         map[uint]uint       // player ID -> party ID, kept by the party package
+
+	contacts  []Contact // touching pairs found by the collision system this tick
+	layout    MapLayout
+	infinite  bool                  // the world has no bounds and is made of chunks, see chunk.go
+	mapTuning definitions.MapTuning // chunks are generated with, fixed when the map is loaded
+	chunks    map[[2]int]*chunk     // loaded chunks by chunk coordinates
+	flow      flowFields
+
+	events []Event
+	deaths []PlayerDeath
+}
+
+// Event is a message produced by a system during a tick, sent to every client
+// once the tick is over.
+type Event struct {
+	Type string
+	Data any
+}
+
+func NewWorld() *World {
+	w := &World{
+		entities:        make(map[EntityID]struct{}),
+		Transforms:      newStore[Transform](),
+		Healths:         newStore[Health](),
+		Weapons:         newStore[Armament](),
+		AIs:             newStore[AI](),
+		Colliders:       newStore[Collider](),
+		Owners:          newStore[Owner](),
+		Motions:         newStore[Motion](),
+		Controls:        newStore[Controller](),
+		Projectiles:     newStore[Ballistics](),
+		Collectibles:    newStore[Collectible](),
+		Buffs:           newStore[Buffs](),
+		Downed:          newStore[Downed](),
+		Runs:            newStore[Run](),
+		Passives:        newStore[Passives](),
+		Modifiers:       newStore[Modifiers](),
+		Progressions:    newStore[Progression](),
+		Bosses:          newStore[Boss](),
+		Strikes:         newStore[Strike](),
+		Terrains:        newStore[Terrain](),
+		Players:         newStore[Player](),
+		playerEntities:  make(map[uint]EntityID),
+		sessionEntities: make(map[string]EntityID),
+		respawnAt:       make(map[uint]time.Time),
+		parties:         make(map[uint]uint),
+	}
+
+	w.OnRemove(w.Transforms.Remove)
+	w.OnRemove(w.Healths.Remove)
+	w.OnRemove(w.Weapons.Remove)
+	w.OnRemove(w.AIs.Remove)
+	w.OnRemove(w.Colliders.Remove)
+	w.OnRemove(w.Owners.Remove)
+	w.OnRemove(w.Motions.Remove)
+	w.OnRemove(w.Projectiles.Remove)
+	w.OnRemove(w.Collectibles.Remove)
+	w.OnRemove(w.Buffs.Remove)
+	w.OnRemove(w.Downed.Remove)
+	w.OnRemove(w.Runs.Remove)
+	w.OnRemove(w.Passives.Remove)
+	w.OnRemove(w.Modifiers.Remove)
+	w.OnRemove(w.Progressions.Remove)
+	w.OnRemove(w.Bosses.Remove)
+	w.OnRemove(w.Strikes.Remove)
+	w.OnRemove(w.Terrains.Remove)
+	w.OnRemove(func(id EntityID) {
+		if controller, ok := w.Controls.Get(id); ok {
+			delete(w.sessionEntities, controller.SessionID)
+			w.Controls.Remove(id)
+		}
+	})
+	w.OnRemove(func(id EntityID) {
+		if player, ok := w.Players.Get(id); ok {
+			delete(w.playerEntities, player.ID)
+			w.Players.Remove(id)
+		}
+	})
+
+	return w
+}
+
+// OnRemove registers a function run whenever an entity is removed, stores
+// added by other files register their Remove here.
+func (w *World) OnRemove(remove func(id EntityID)) {
+	w.removers = append(w.removers, remove)
+}
+
+func (w *World) NewEntity() EntityID {
+	w.nextID++
+	w.entities[w.nextID] = struct{}{}
+	return w.nextID
+}
+
+// RemoveEntity removes the entity and all of its components.
+func (w *World) RemoveEntity(id EntityID) {
+	if _, ok := w.entities[id]; !ok {
+		return
+	}
+	delete(w.entities, id)
+	for _, remove := range w.removers {
+		remove(id)
+	}
+}
+
+func (w *World) Exists(id EntityID) bool {
+	_, ok := w.entities[id]
+	return ok
+}
+
+// Emit queues an event for the clients.
+func (w *World) Emit(eventType string, data any) {
+	w.events = append(w.events, Event{Type: eventType, Data: data})
+}
+
+func (w *World) flushEvents() []Event {
+	events := w.events
+	w.events = nil
+	return events
+}
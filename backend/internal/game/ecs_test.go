@@ -0,0 +1,138 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+type testComponent struct {
+	Value int
+}
+
+func TestStoreAddGetRemove(t *testing.T) {
+	s := newStore[testComponent]()
+
+	stored := s.Add(3, testComponent{Value: 1})
+	stored.Value = 2
+	if got, ok := s.Get(3); !ok || got.Value != 2 {
+		t.Fatalf("Get(3) = %v, %v, want the stored pointer", got, ok)
+	}
+
+	s.Add(3, testComponent{Value: 5})
+	if got, _ := s.Get(3); got.Value != 5 || s.Len() != 1 {
+		t.Fatalf("Add did not replace the component, got %v with %d components", got, s.Len())
+	}
+
+	s.Remove(3)
+	s.Remove(4)
+	if _, ok := s.Get(3); ok || s.Has(3) || s.Len() != 0 {
+		t.Fatal("Remove kept the component")
+	}
+}
+
+func TestStoreEachOrder(t *testing.T) {
+	s := newStore[testComponent]()
+	for _, id := range []EntityID{5, 1, 9, 3} {
+		s.Add(id, testComponent{Value: int(id)})
+	}
+
+	var visited []EntityID
+	s.Each(func(id EntityID, component *testComponent) {
+		if component.Value != int(id) {
+			t.Errorf("entity %d got component %v", id, component)
+		}
+		visited = append(visited, id)
+	})
+	if want := []EntityID{1, 3, 5, 9}; !slices.Equal(visited, want) || !slices.Equal(s.IDs(), want) {
+		t.Fatalf("visited %v, IDs %v, want %v", visited, s.IDs(), want)
+	}
+}
+
+func TestStoreEachRemoving(t *testing.T) {
+	tests := []struct {
+		name    string
+		remove  func(s *Store[testComponent], id EntityID)
+		visited []EntityID
+		left    []EntityID
+	}{
+		{
+			name:    "itself",
+			remove:  func(s *Store[testComponent], id EntityID) { s.Remove(id) },
+			visited: []EntityID{1, 2, 3, 4},
+			left:    []EntityID{},
+		},
+		{
+			name: "a later entity",
+			remove: func(s *Store[testComponent], id EntityID) {
+				if id == 1 {
+					s.Remove(3)
+				}
+			},
+			visited: []EntityID{1, 2, 4},
+			left:    []EntityID{1, 2, 4},
+		},
+		{
+			name: "an earlier entity",
+			remove: func(s *Store[testComponent], id EntityID) {
+				if id == 4 {
+					s.Remove(1)
+				}
+			},
+			visited: []EntityID{1, 2, 3, 4},
+			left:    []EntityID{2, 3, 4},
+		},
+		{
+			name: "adding an entity",
+			remove: func(s *Store[testComponent], id EntityID) {
+				if id == 2 {
+					s.Add(10, testComponent{})
+				}
+			},
+			visited: []EntityID{1, 2, 3, 4},
+			left:    []EntityID{1, 2, 3, 4, 10},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStore[testComponent]()
+			for id := EntityID(1); id <= 4; id++ {
+				s.Add(id, testComponent{})
+			}
+
+			var visited []EntityID
+			s.Each(func(id EntityID, component *testComponent) {
+				if component == nil {
+					t.Fatalf("entity %d visited without its component", id)
+				}
+				visited = append(visited, id)
+				test.remove(&s, id)
+			})
+			if !slices.Equal(visited, test.visited) || !slices.Equal(s.IDs(), test.left) {
+				t.Errorf("visited %v and left %v, want %v and %v", visited, s.IDs(), test.visited, test.left)
+			}
+		})
+	}
+}
+
+func TestWorldRemoveEntity(t *testing.T) {
+	w := NewWorld()
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{})
+	w.Healths.Add(id, Health{HP: 1, MaxHP: 1})
+	w.Players.Add(id, Player{ID: 7})
+	w.playerEntities[7] = id
+
+	w.RemoveEntity(id)
+	if w.Transforms.Has(id) || w.Healths.Has(id) || w.Players.Has(id) {
+		t.Fatal("RemoveEntity kept components")
+	}
+	if _, ok := w.PlayerEntity(7); ok {
+		t.Fatal("RemoveEntity kept the player entity")
+	}
+	w.RemoveEntity(id)
+
+	if next := w.NewEntity(); next == id {
+		t.Fatalf("entity ID %d was reused", id)
+	}
+}
@@ -5,11 +5,41 @@ package game
 var (
 	ClientKeyPressed    = "ClientKeyPressed"    // data -> Key
 	ClientKeyDown       = "ClientKeyDown"       // data -> Key
-	ClientPlayerSpawn   = "ClientPlayerSpawn"   // empty request
+	ClientPlayerSpawn   = "ClientPlayerSpawn"   // data -> RunMode, optional
 	ClientPlayerDespawn = "ClientPlayerDespawn" // empty request
 	ClientSelect        = "ClientSelect"        // data -> selection number out of one, two, or three
+	ClientChatSend      = "ClientChatSend"      // data -> ChatRequest
+
+	ClientPartyInvite     = "ClientPartyInvite"     // data -> username
+	ClientPartyInviteClan = "ClientPartyInviteClan" // empty request, invites every online clanmate
+	ClientPartyAccept     = "ClientPartyAccept"     // data -> party ID
+	ClientPartyDecline    = "ClientPartyDecline"    // data -> party ID
+	ClientPartyLeave      = "ClientPartyLeave"      // empty request
+	ClientPartyKick       = "ClientPartyKick"       // data -> username
 )
 
+// ClientEvents lists every message type clients may send.
+var ClientEvents = []string{
+	ClientKeyPressed,
+	ClientKeyDown,
+	ClientPlayerSpawn,
+	ClientPlayerDespawn,
+	ClientSelect,
+	ClientChatSend,
+	ClientPartyInvite,
+	ClientPartyInviteClan,
+	ClientPartyAccept,
+	ClientPartyDecline,
+	ClientPartyLeave,
+	ClientPartyKick,
+}
+
+type ChatRequest struct {
+	Channel string `json:"channel"`
+	To      string `json:"to,omitempty"` // recipient username of direct messages
+	Text    string `json:"text"`
+}
+
 var (
 	KeyW = 'w'
 	KeyA = 'a'
@@ -24,13 +54,30 @@ var (
 // Server
 type (
 	MovedEntity struct {
-		ID       uint    `json:"id"`
-		Position [2]uint `json:"position"`
+		ID       uint   `json:"id"`
+		Position [2]int `json:"position"`
 	}
 
+	// PlayerUpgrade is offered to a player reaching a level, they pick one
+	// of the choices with ClientSelect.
 	PlayerUpgrade struct {
-		ID             uint    `json:"id"`
-		WeaponClassIDs [3]uint `json:"weapon_class_ids"`
+		ID      uint            `json:"id"`    // of the player
+		Level   uint            `json:"level"` // reached
+		Choices []UpgradeChoice `json:"choices"`
+	}
+
+	UpgradeChoice struct {
+		Kind          string `json:"kind"` // UpgradeWeapon, UpgradePassive or UpgradeEvolution
+		WeaponClassID uint   `json:"weapon_class_id,omitempty"`
+		EvolvesFrom   uint   `json:"evolves_from,omitempty"` // weapon class evolving into WeaponClassID
+		Passive       string `json:"passive,omitempty"`      // key of the passive definition
+		Level         uint   `json:"level"`                  // of the weapon or passive once chosen
+	}
+
+	// UpgradeResult is the choice a player made.
+	UpgradeResult struct {
+		PlayerID uint          `json:"player_id"`
+		Choice   UpgradeChoice `json:"choice"`
 	}
 )
 
@@ -44,4 +91,75 @@ var (
 	ServerDamagePlayers  = "ServerDamagePlayers"  // data -> []PlayerID
 	ServerDamageEnemies  = "ServerDamageEnemies"  // data -> []EnemyID
 	ServerUpgradePlayer  = "ServerUpgradePlayer"  // data -> []PlayerUpgrade
+	ServerUpgradeResult  = "ServerUpgradeResult"  // data -> []UpgradeResult
+	ServerChatMessage    = "ServerChatMessage"    // data -> ChatMessage
+	ServerChatError      = "ServerChatError"      // data -> error string
+	ServerFriendPresence = "ServerFriendPresence" // data -> Friend
+	ServerFriendRequest  = "ServerFriendRequest"  // data -> Friend
+
+	ServerPartyUpdate = "ServerPartyUpdate" // data -> Party
+	ServerPartyInvite = "ServerPartyInvite" // data -> PartyInvite
+	ServerPartyLeave  = "ServerPartyLeave"  // data -> party ID, sent to members who left or were kicked
+	ServerPartyError  = "ServerPartyError"  // data -> error message
+	ServerShutdown    = "ServerShutdown"    // data -> reason string
+
+	ServerProjectilesSpawn   = "ServerProjectilesSpawn"   // data -> []Projectile
+	ServerProjectilesDespawn = "ServerProjectilesDespawn" // data -> []ProjectileID
+
+	ServerPickupsSpawn   = "ServerPickupsSpawn"   // data -> []Pickup
+	ServerPickupsDespawn = "ServerPickupsDespawn" // data -> []PickupID
+	ServerPickupsCollect = "ServerPickupsCollect" // data -> []PickupCollect
+	ServerMovePickups    = "ServerMovePickups"    // data -> []MovedEntity
+
+	ServerPlayersDown      = "ServerPlayersDown"      // data -> []DownedPlayer
+	ServerPlayersRevive    = "ServerPlayersRevive"    // data -> []PlayerID
+	ServerPlayersDeath     = "ServerPlayersDeath"     // data -> []RunSummary
+	ServerPlayerSpawnError = "ServerPlayerSpawnError" // data -> error string
+
+	ServerBossHealth    = "ServerBossHealth"    // data -> []BossHealth
+	ServerBossTelegraph = "ServerBossTelegraph" // data -> []Telegraph
+	ServerBossStrike    = "ServerBossStrike"    // data -> []TelegraphID
+
+	ServerMapLayout       = "ServerMapLayout"       // data -> MapLayout
+	ServerMapChunksLoad   = "ServerMapChunksLoad"   // data -> []MapChunk
+	ServerMapChunksUnload = "ServerMapChunksUnload" // data -> [][2]int
 )
+
+// ServerEvents lists every message type the server may send.
+var ServerEvents = []string{
+	ServerPlayersSpawn,
+	ServerPlayersDespawn,
+	ServerEnemiesSpawn,
+	ServerEnemiesDespawn,
+	ServerMovePlayers,
+	ServerMoveEnemies,
+	ServerDamagePlayers,
+	ServerDamageEnemies,
+	ServerUpgradePlayer,
+	ServerUpgradeResult,
+	ServerChatMessage,
+	ServerChatError,
+	ServerFriendPresence,
+	ServerFriendRequest,
+	ServerPartyUpdate,
+	ServerPartyInvite,
+	ServerPartyLeave,
+	ServerPartyError,
+	ServerShutdown,
+	ServerProjectilesSpawn,
+	ServerProjectilesDespawn,
+	ServerPickupsSpawn,
+	ServerPickupsDespawn,
+	ServerPickupsCollect,
+	ServerMovePickups,
+	ServerPlayersDown,
+	ServerPlayersRevive,
+	ServerPlayersDeath,
+	ServerPlayerSpawnError,
+	ServerBossHealth,
+	ServerBossTelegraph,
+	ServerBossStrike,
+	ServerMapLayout,
+	ServerMapChunksLoad,
+	ServerMapChunksUnload,
+}
@@ -0,0 +1,208 @@
+package game
+
+// flowCellSize is the size of the cells flow fields are computed over, paths
+// going through gaps between obstacles of at least two cells.
+const flowCellSize = 32
+
+// flowRadius is how many cells a flow field reaches around its target,
+// enemies further away chase in a straight line.
+const flowRadius = 48
+
+// flowSide is the number of cells along a side of a flow field.
+const flowSide = 2*flowRadius + 1
+
+// flowClearance is how far around obstacles cells are blocked, so that paths
+// keep enemies wider than a cell off their corners.
+const flowClearance = flowCellSize / 2
+
+// flowTolerance is how far from the center line of the cells of its path an
+// entity may be before steering back towards it.
+const flowTolerance = flowCellSize / 8
+
+// flowUnreached marks the cells a flow field found no path from.
+const flowUnreached = -1
+
+// flowNeighbors are the steps from a cell to its neighbors, orthogonal ones
+// first.
+var flowNeighbors = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// flowField tells every cell around a target the direction of the shortest
+// path to it around obstacles. It is shared by every enemy chasing the target
+// and computed again in full, over flowSide² cells, only when the target moves
+// to another cell or obstacles change. Moving the target changes the distance
+// of every cell, so a field is never patched up from its previous distances.
+type flowField struct {
+	center    [2]int // cell of the target when the field was computed
+	obstacles uint64 // version of the blocked cells the field was computed with
+	distances []int  // steps to the center per cell, flowUnreached if none
+	steps     [][2]int
+}
+
+// flowFields holds the flow field of every chased player and the cells
+// obstacles block, rebuilt whenever obstacles are added or removed.
+type flowFields struct {
+	fields    map[EntityID]*flowField // player entity -> field
+	blocked   map[[2]int]struct{}
+	obstacles uint64 // version of the obstacles, bumped when they change
+	built     uint64 // version blocked was built from
+}
+
+// flowCell returns the flow field cell holding the position.
+func flowCell(position [2]int) [2]int {
+	return [2]int{floorDiv(position[0], flowCellSize), floorDiv(position[1], flowCellSize)}
+}
+
+// index returns the index of the cell in the field, false if the field does
+// not reach it.
+func (f *flowField) index(cell [2]int) (int, bool) {
+	x := cell[0] - f.center[0] + flowRadius
+	y := cell[1] - f.center[1] + flowRadius
+	if x < 0 || y < 0 || x >= flowSide || y >= flowSide {
+		return 0, false
+	}
+	return y*flowSide + x, true
+}
+
+// direction returns the direction to move in from the position to follow the
+// field, false if the field has no path from there. Entities head for the
+// center of the next cell rather than along the step, so that their sides do
+// not catch on the corners of the obstacles the path goes around.
+func (f *flowField) direction(position [2]int) ([2]int, bool) {
+	cell := flowCell(position)
+	i, ok := f.index(cell)
+	if !ok || f.steps[i] == [2]int{} {
+		return [2]int{}, false
+	}
+
+	direction := f.steps[i]
+	for axis := range 2 {
+		center := (cell[axis]+direction[axis])*flowCellSize + flowCellSize/2
+		if offset := center - position[axis]; offset > flowTolerance || offset < -flowTolerance {
+			direction[axis] = sign(offset)
+		}
+	}
+	return direction, true
+}
+
+// invalidateFlowFields makes every flow field be computed again, to be called
+// when obstacles are added or removed.
+func (w *World) invalidateFlowFields() {
+	w.flow.obstacles++
+}
+
+// blockedCells returns the cells within the clearance of an obstacle.
+func (w *World) blockedCells() map[[2]int]struct{} {
+	if w.flow.blocked != nil && w.flow.built == w.flow.obstacles {
+		return w.flow.blocked
+	}
+
+	blocked := make(map[[2]int]struct{})
+	w.Colliders.Each(func(id EntityID, collider *Collider) {
+		if !collider.Static {
+			return
+		}
+		box := w.box(id).grow(flowClearance)
+		from, to := flowCell(box.Min), flowCell([2]int{box.Max[0] - 1, box.Max[1] - 1})
+		for x := from[0]; x <= to[0]; x++ {
+			for y := from[1]; y <= to[1]; y++ {
+				blocked[[2]int{x, y}] = struct{}{}
+			}
+		}
+	})
+	w.flow.blocked, w.flow.built = blocked, w.flow.obstacles
+	return blocked
+}
+
+// flowField returns the flow field towards the player, computing it again
+// only when the player moved to another cell or obstacles changed since. The
+// field's buffers are reused, but its distances are computed from scratch.
+func (w *World) flowField(player EntityID) *flowField {
+	transform, ok := w.Transforms.Get(player)
+	if !ok {
+		return nil
+	}
+
+	center := flowCell(transform.Position)
+	field := w.flow.fields[player]
+	if field != nil && field.center == center && field.obstacles == w.flow.obstacles {
+		return field
+	}
+	if field == nil {
+		field = &flowField{distances: make([]int, flowSide*flowSide), steps: make([][2]int, flowSide*flowSide)}
+		if w.flow.fields == nil {
+			w.flow.fields = make(map[EntityID]*flowField)
+		}
+		w.flow.fields[player] = field
+	}
+	field.center, field.obstacles = center, w.flow.obstacles
+	field.compute(w.blockedCells())
+	flowComputes.Inc()
+	return field
+}
+
+// compute fills the distances with a breadth-first search from the center
+// then points every cell at its closest neighbor. Diagonal steps cost the same
+// as orthogonal ones like they do for moving entities, but may not cut the
+// corner of a blocked cell.
+func (f *flowField) compute(blocked map[[2]int]struct{}) {
+	walls := make([]bool, flowSide*flowSide)
+	for i := range walls {
+		cell := [2]int{f.center[0] - flowRadius + i%flowSide, f.center[1] - flowRadius + i/flowSide}
+		_, walls[i] = blocked[cell]
+		f.distances[i] = flowUnreached
+	}
+
+	// neighbor returns the index of the cell one step away, false if it is
+	// out of the field or the step cuts the corner of a blocked cell when
+	// that is not allowed. Both cells a diagonal step passes by are in the
+	// field when its ends are.
+	neighbor := func(i int, step [2]int, cutCorners bool) (int, bool) {
+		x, y := i%flowSide+step[0], i/flowSide+step[1]
+		if x < 0 || y < 0 || x >= flowSide || y >= flowSide {
+			return 0, false
+		}
+		if !cutCorners && step[0] != 0 && step[1] != 0 && (walls[i+step[0]] || walls[i+step[1]*flowSide]) {
+			return 0, false
+		}
+		return y*flowSide + x, true
+	}
+
+	start := flowRadius*flowSide + flowRadius
+	f.distances[start] = 0
+	queue := make([]int, 1, len(walls))
+	queue[0] = start
+	for len(queue) != 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, step := range flowNeighbors {
+			j, ok := neighbor(i, step, false)
+			if ok && !walls[j] && f.distances[j] == flowUnreached {
+				f.distances[j] = f.distances[i] + 1
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	// Blocked cells get a step too, so enemies pushed against an obstacle
+	// step out of it towards the path.
+	for i := range f.steps {
+		f.steps[i] = [2]int{}
+		best := f.distances[i]
+		for _, step := range flowNeighbors {
+			j, ok := neighbor(i, step, walls[i])
+			if !ok || f.distances[j] == flowUnreached || (best != flowUnreached && f.distances[j] >= best) {
+				continue
+			}
+			best, f.steps[i] = f.distances[j], step
+		}
+	}
+}
+
+// pruneFlowFields drops the fields of players no enemy chases anymore.
+func (w *World) pruneFlowFields(chased map[EntityID]struct{}) {
+	for player := range w.flow.fields {
+		if _, ok := chased[player]; !ok {
+			delete(w.flow.fields, player)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package game
+
+import "testing"
+
+// computeField computes a flow field centered on cell 0, 0 with the cells
+// blocked.
+func computeField(blocked ...[2]int) *flowField {
+	cells := make(map[[2]int]struct{}, len(blocked))
+	for _, cell := range blocked {
+		cells[cell] = struct{}{}
+	}
+	field := &flowField{distances: make([]int, flowSide*flowSide), steps: make([][2]int, flowSide*flowSide)}
+	field.compute(cells)
+	return field
+}
+
+func (f *flowField) at(cell [2]int) (int, [2]int) {
+	i, ok := f.index(cell)
+	if !ok {
+		return flowUnreached, [2]int{}
+	}
+	return f.distances[i], f.steps[i]
+}
+
+// cellCenter returns the position at the center of the cell.
+func cellCenter(cell [2]int) [2]int {
+	return [2]int{cell[0]*flowCellSize + flowCellSize/2, cell[1]*flowCellSize + flowCellSize/2}
+}
+
+func TestFlowCell(t *testing.T) {
+	tests := []struct {
+		position, want [2]int
+	}{
+		{[2]int{0, 0}, [2]int{0, 0}},
+		{[2]int{flowCellSize - 1, flowCellSize}, [2]int{0, 1}},
+		{[2]int{-1, -flowCellSize}, [2]int{-1, -1}},
+		{[2]int{-flowCellSize - 1, 5}, [2]int{-2, 0}},
+	}
+
+	for _, test := range tests {
+		if got := flowCell(test.position); got != test.want {
+			t.Errorf("flowCell(%v) = %v, want %v", test.position, got, test.want)
+		}
+	}
+}
+
+func TestFlowFieldOpen(t *testing.T) {
+	field := computeField()
+
+	tests := []struct {
+		cell     [2]int
+		distance int
+		step     [2]int
+	}{
+		{[2]int{0, 0}, 0, [2]int{}},
+		{[2]int{3, 0}, 3, [2]int{-1, 0}},
+		{[2]int{0, -2}, 2, [2]int{0, 1}},
+		{[2]int{2, 2}, 2, [2]int{-1, -1}},
+		{[2]int{-4, 1}, 4, [2]int{1, 0}},
+		{[2]int{flowRadius, -flowRadius}, flowRadius, [2]int{-1, 1}},
+	}
+
+	for _, test := range tests {
+		distance, step := field.at(test.cell)
+		if distance != test.distance || step != test.step {
+			t.Errorf("cell %v = %d, %v, want %d, %v", test.cell, distance, step, test.distance, test.step)
+		}
+	}
+}
+
+func TestFlowFieldAroundWall(t *testing.T) {
+	// A wall from 2, -3 to 2, 3 makes cells behind it go around its ends,
+	// without cutting their corners: 4 steps up to 1, 4, one past the end to
+	// 2, 4 and 3, 4, then 4 back down to 3, 0.
+	var wall [][2]int
+	for y := -3; y <= 3; y++ {
+		wall = append(wall, [2]int{2, y})
+	}
+	field := computeField(wall...)
+
+	if distance, _ := field.at([2]int{3, 0}); distance != 10 {
+		t.Errorf("distance behind the wall = %d, want 10", distance)
+	}
+	if distance, _ := field.at([2]int{1, 0}); distance != 1 {
+		t.Errorf("distance in front of the wall = %d, want 1", distance)
+	}
+	if distance, step := field.at([2]int{2, 0}); distance != flowUnreached || step != [2]int{-1, 0} {
+		t.Errorf("blocked cell = %d, %v, want unreached stepping out towards the center", distance, step)
+	}
+
+	// Following the steps from behind the wall reaches the center without
+	// entering it.
+	cell := [2]int{3, 0}
+	for range 10 {
+		distance, step := field.at(cell)
+		next, _ := field.at([2]int{cell[0] + step[0], cell[1] + step[1]})
+		if next != distance-1 {
+			t.Fatalf("step %v from %v does not get one closer", step, cell)
+		}
+		cell = [2]int{cell[0] + step[0], cell[1] + step[1]}
+		if cell[0] == 2 && cell[1] >= -3 && cell[1] <= 3 {
+			t.Fatalf("path went through the wall at %v", cell)
+		}
+	}
+	if cell != [2]int{0, 0} {
+		t.Errorf("path ended at %v, want the center", cell)
+	}
+}
+
+func TestFlowFieldCorners(t *testing.T) {
+	// 1, 1 may not step diagonally to the center past the blocked 1, 0.
+	field := computeField([2]int{1, 0})
+
+	distance, step := field.at([2]int{1, 1})
+	if distance != 2 {
+		t.Errorf("distance past the corner = %d, want 2", distance)
+	}
+	if step != [2]int{-1, 0} {
+		t.Errorf("step past the corner = %v, want [-1 0]", step)
+	}
+
+	// -1, 1 still steps diagonally to the center, the cells it passes by
+	// being free.
+	if distance, step := field.at([2]int{-1, 1}); distance != 1 || step != [2]int{1, -1} {
+		t.Errorf("free diagonal = %d, %v, want 1, [1 -1]", distance, step)
+	}
+}
+
+func TestFlowFieldUnreachable(t *testing.T) {
+	// A ring of blocked cells around 5, 5 cuts it off from the center.
+	var ring [][2]int
+	for x := 4; x <= 6; x++ {
+		for y := 4; y <= 6; y++ {
+			if x != 5 || y != 5 {
+				ring = append(ring, [2]int{x, y})
+			}
+		}
+	}
+	field := computeField(ring...)
+
+	if distance, step := field.at([2]int{5, 5}); distance != flowUnreached || step != [2]int{} {
+		t.Errorf("enclosed cell = %d, %v, want unreached without a step", distance, step)
+	}
+	if _, ok := field.direction(cellCenter([2]int{5, 5})); ok {
+		t.Error("direction found from an enclosed cell")
+	}
+	if _, step := field.at([2]int{4, 4}); step != [2]int{-1, -1} {
+		t.Errorf("blocked ring cell steps %v, want [-1 -1] out of the ring", step)
+	}
+	if _, ok := field.direction(cellCenter([2]int{flowRadius + 1, 0})); ok {
+		t.Error("direction found outside of the field")
+	}
+}
+
+func TestFlowFieldDirection(t *testing.T) {
+	field := computeField()
+
+	tests := []struct {
+		name     string
+		position [2]int
+		want     [2]int
+	}{
+		{"on the center line", cellCenter([2]int{3, 0}), [2]int{-1, 0}},
+		{"within tolerance", [2]int{3*flowCellSize + flowCellSize/2, flowCellSize/2 + flowTolerance}, [2]int{-1, 0}},
+		{"past tolerance steers back", [2]int{3*flowCellSize + flowCellSize/2, flowCellSize - 1}, [2]int{-1, -1}},
+		{"diagonal", cellCenter([2]int{-2, -2}), [2]int{1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := field.direction(test.position)
+			if !ok || got != test.want {
+				t.Errorf("direction = %v, %v, want %v", got, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestFlowFieldInvalidation(t *testing.T) {
+	w := NewWorld()
+	player := w.NewEntity()
+	w.Transforms.Add(player, Transform{Position: cellCenter([2]int{0, 0})})
+
+	field := w.flowField(player)
+	if distance, _ := field.at([2]int{2, 0}); distance != 2 {
+		t.Fatalf("distance = %d, want 2", distance)
+	}
+	version := field.obstacles
+
+	if again := w.flowField(player); again != field || again.obstacles != version {
+		t.Fatal("field was computed again without changes")
+	}
+
+	w.SpawnObstacle(cellCenter([2]int{2, 0}), flowCellSize)
+	field = w.flowField(player)
+	if field.obstacles == version {
+		t.Fatal("field was not computed again after an obstacle spawned")
+	}
+	if distance, _ := field.at([2]int{2, 0}); distance != flowUnreached {
+		t.Errorf("distance of the obstacle's cell = %d, want unreached", distance)
+	}
+
+	transform, _ := w.Transforms.Get(player)
+	transform.Position = cellCenter([2]int{-10, 0})
+	field = w.flowField(player)
+	if field.center != [2]int{-10, 0} {
+		t.Errorf("center = %v after the player moved, want [-10 0]", field.center)
+	}
+
+	w.pruneFlowFields(map[EntityID]struct{}{})
+	if len(w.flow.fields) != 0 {
+		t.Error("fields of players no one chases were kept")
+	}
+}
@@ -1,29 +1,247 @@
 package game
 
 import (
+	"context"
 	"log"
+	"sync/atomic"
 	"time"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
 	"valley-of-survival-dawn-of-squares/internal/ws"
 )
 
-var tickRate = 60
-var tickInterval = time.Second / time.Duration(tickRate)
+// tickInterval follows the tick rate of the tuning, it only changes between
+// two ticks when new definitions are applied.
+var tickInterval atomic.Int64
 
-func StartWorld() {
-	ticker := time.NewTicker(tickInterval)
+func init() {
+	setTickRate(definitions.DefaultTuning.TickRate)
+}
+
+func setTickRate(rate uint) {
+	tickInterval.Store(int64(time.Second / time.Duration(rate)))
+}
+
+// maxCatchUpTicks bounds how many ticks are run back to back after the loop
+// fell behind, the remaining backlog is dropped so a stall does not turn into
+// a burst of fast-forwarded simulation.
+var maxCatchUpTicks = 5
+
+var (
+	currentTick      atomic.Uint64
+	lastTickAt       atomic.Int64 // unix nanoseconds
+	lastTickDuration atomic.Int64
+
+	tickDurations   = metrics.NewHistogram("game_tick_duration_seconds", "Time spent updating the world per tick.", metrics.DefaultBuckets)
+	systemDurations = metrics.NewHistogram("game_system_duration_seconds", "Time spent in each system per tick.", metrics.DefaultBuckets, "system")
+	tickOverruns    = metrics.NewCounter("game_tick_overruns_total", "Ticks that took longer than the tick interval, by slowest system.", "system")
+	droppedTicks    = metrics.NewCounter("game_ticks_dropped_total", "Ticks skipped because the loop fell too far behind.")
+	flowComputes    = metrics.NewCounter("game_flow_fields_computed_total", "Flow fields computed for enemies to chase players around obstacles.")
+	_               = metrics.NewGaugeVecFunc("game_entities", "Number of entities in the world.", "kind", func() map[string]float64 {
+		world.RLock()
+		defer world.RUnlock()
+
+		return map[string]float64{
+			"all":        float64(len(world.entities)),
+			"player":     float64(world.Players.Len()),
+			"enemy":      float64(world.AIs.Len()),
+			"projectile": float64(world.Projectiles.Len()),
+			"pickup":     float64(world.Collectibles.Len()),
+			"strike":     float64(world.Strikes.Len()),
+			"chunk":      float64(len(world.chunks)),
+		}
+	})
+)
+
+// System is a part of the world updated once per tick. Systems run in the
+// order they were registered with the world locked and receive the number of
+// the current tick, the simulated time always advances by TickInterval
+// between two ticks.
+type System struct {
+	Name   string
+	Update func(w *World, tick uint64)
+}
+
+var systems = []System{
+	{"input", updateInput},
+	{"chunks", updateChunks},
+	{"spawner", updateSpawner},
+	{"ai", updateAI},
+	{"movement", updateMovement},
+	{"pickups", updatePickups},
+	{"upgrades", updateUpgrades},
+	{"projectiles", updateProjectiles},
+	{"weapons", updateWeapons},
+	{"combat", updateCombat},
+	{"terrain", updateTerrain},
+	{"bosses", updateBosses},
+	{"revive", updateRevive},
+	{"reaper", updateReaper},
+	{"buffs", updateBuffs},
+}
+
+// RegisterSystem adds a system run after the already registered ones. It must
+// be called before StartWorld.
+func RegisterSystem(name string, update func(w *World, tick uint64)) {
+	systems = append(systems, System{Name: name, Update: update})
+}
+
+// InputHandler applies a client message to the world, it runs during the
+// input system with the world locked.
+type InputHandler func(w *World, message ws.Message)
+
+var inputHandlers = map[string]InputHandler{
+	ClientKeyDown:    handleKey,
+	ClientKeyPressed: handleKey,
+	ClientSelect:     handleSelect,
+}
+
+// RegisterInputHandler routes the client messages of the given type read from
+// the hub to handler. It must be called before StartWorld.
+func RegisterInputHandler(messageType string, handler InputHandler) {
+	inputHandlers[messageType] = handler
+}
+
+// TickInterval is the time budget of a single tick.
+func TickInterval() time.Duration {
+	return time.Duration(tickInterval.Load())
+}
+
+// CurrentTick returns the number of the last tick run, starting at 1.
+func CurrentTick() uint64 {
+	return currentTick.Load()
+}
+
+// LastTick returns when the last tick finished and how long it took, the time
+// is zero until the first tick.
+func LastTick() (time.Time, time.Duration) {
+	at := lastTickAt.Load()
+	if at == 0 {
+		return time.Time{}, 0
+	}
+	return time.Unix(0, at), time.Duration(lastTickDuration.Load())
+}
+
+// StartWorld runs the world until ctx is done. It only stops between ticks so
+// the state is never left half updated.
+//
+// Elapsed time is accumulated and consumed in fixed steps of the tick interval
+// so the simulation speed does not depend on how late the ticker fires.
+// Definitions passed to ApplyDefinitions are swapped in between two ticks.
+func StartWorld(ctx context.Context) {
+	setTickRate(definitions.Current().Tuning.TickRate)
+	interval := TickInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		updateWorld()
+	previous := time.Now()
+	var accumulator time.Duration
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-reloads:
+			request.result <- applyDefinitions(request.set)
+			if next := TickInterval(); next != interval {
+				log.Printf("Tick interval changed from %s to %s", interval, next)
+				interval = next
+				ticker.Reset(interval)
+			}
+		case now := <-ticker.C:
+			steps, dropped, left := tickSteps(accumulator+now.Sub(previous), interval)
+			accumulator, previous = left, now
+
+			if dropped != 0 {
+				log.Printf("World is %d ticks behind, dropping them", dropped)
+				droppedTicks.Add(float64(dropped))
+			}
+
+			for range steps {
+				runTick()
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// tickSteps splits the accumulated time into the ticks to run, at most
+// maxCatchUpTicks, the ticks dropped past those and the time left over for
+// the next frame.
+func tickSteps(accumulator time.Duration, interval time.Duration) (steps int, dropped int, left time.Duration) {
+	due := int(accumulator / interval)
+	steps = min(due, maxCatchUpTicks)
+	return steps, due - steps, accumulator % interval
+}
+
+func runTick() {
+	tick := currentTick.Add(1)
+	start := time.Now()
+
+	var slowest string
+	var slowestDuration time.Duration
+
+	world.Lock()
+	for _, system := range systems {
+		systemStart := time.Now()
+		system.Update(world, tick)
+		duration := time.Since(systemStart)
+
+		systemDurations.Observe(duration.Seconds(), system.Name)
+		if duration > slowestDuration {
+			slowest, slowestDuration = system.Name, duration
+		}
+	}
+	events := world.flushEvents()
+	deaths := world.flushDeaths()
+	world.Unlock()
+
+	publishEvents(events)
+	for _, death := range deaths {
+		for _, handler := range deathHandlers {
+			go handler(death)
+		}
+	}
+
+	duration := time.Since(start)
+	tickDurations.Observe(duration.Seconds())
+	lastTickDuration.Store(int64(duration))
+	lastTickAt.Store(time.Now().UnixNano())
+
+	if interval := TickInterval(); duration > interval {
+		tickOverruns.Inc(slowest)
+		log.Printf("Tick %d took %s, over the %s budget, slowest system %s took %s", tick, duration, interval, slowest, slowestDuration)
+	}
+}
+
+// updateInput applies the client messages queued since the last tick.
+func updateInput(w *World, tick uint64) {
+	hub := ws.GetHub()
+
+	for range len(hub.Input) {
+		message := <-hub.Input
+
+		handler, ok := inputHandlers[message.Type]
+		if !ok {
+			log.Println("Unhandled client message:", message.Type)
+			continue
+		}
+		handler(w, message)
 	}
 }
 
-func updateWorld() {
+// publishEvents sends the events of a tick to every client. Events are dropped
+// rather than stalling the loop when the hub falls behind.
+func publishEvents(events []Event) {
 	hub := ws.GetHub()
 
-	select {
-	case message := <-hub.Broadcast:
-		log.Println(message)
-	default:
+	for _, event := range events {
+		select {
+		case hub.Broadcast <- ws.Message{Type: event.Type, Data: event.Data}:
+		default:
+			log.Println("Hub is full, dropping event:", event.Type)
+		}
 	}
 }
@@ -0,0 +1,55 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTickSteps(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	tests := []struct {
+		name        string
+		accumulator time.Duration
+		steps       int
+		dropped     int
+		left        time.Duration
+	}{
+		{"early frame", 4 * time.Millisecond, 0, 0, 4 * time.Millisecond},
+		{"normal frame", interval, 1, 0, 0},
+		{"late frame", 25 * time.Millisecond, 2, 0, 5 * time.Millisecond},
+		{"catching up", 5 * interval, maxCatchUpTicks, 0, 0},
+		{"long stall", time.Second, maxCatchUpTicks, 95, 0},
+		{"long stall with leftover", 123 * time.Millisecond, maxCatchUpTicks, 7, 3 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, dropped, left := tickSteps(tt.accumulator, interval)
+			if steps != tt.steps || dropped != tt.dropped || left != tt.left {
+				t.Errorf("tickSteps(%s) = %d, %d, %s, want %d, %d, %s",
+					tt.accumulator, steps, dropped, left, tt.steps, tt.dropped, tt.left)
+			}
+		})
+	}
+}
+
+func TestTickStepsCarriesLeftover(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	frames := []time.Duration{6 * time.Millisecond, 6 * time.Millisecond, 6 * time.Millisecond, 12 * time.Millisecond}
+	wantSteps := []int{0, 1, 0, 2}
+
+	var accumulator time.Duration
+	total := 0
+	for i, frame := range frames {
+		var steps int
+		steps, _, accumulator = tickSteps(accumulator+frame, interval)
+		if steps != wantSteps[i] {
+			t.Errorf("frame %d ran %d ticks, want %d", i, steps, wantSteps[i])
+		}
+		total += steps
+	}
+
+	if total != 3 || accumulator != 0 {
+		t.Errorf("ran %d ticks with %s left over, want 3 with none", total, accumulator)
+	}
+}
@@ -0,0 +1,35 @@
+package game
+
+import "valley-of-survival-dawn-of-squares/internal/ws"
+
+var keyDirections = map[string][2]int{
+	string(KeyW): {0, -1},
+	string(KeyA): {-1, 0},
+	string(KeyS): {0, 1},
+	string(KeyD): {1, 0},
+}
+
+// handleKey steers the client's entity for the current tick, clients send
+// ClientKeyDown every frame while a key is held. Keys of opposite directions
+// cancel out.
+func handleKey(w *World, message ws.Message) {
+	key, _ := message.Data.(string)
+	direction, ok := keyDirections[key]
+	if !ok {
+		return
+	}
+
+	id, ok := w.SessionEntity(message.SessionID)
+	if !ok {
+		return
+	}
+
+	motion, ok := w.Motions.Get(id)
+	if !ok {
+		return
+	}
+
+	for axis := range 2 {
+		motion.Direction[axis] = max(-1, min(1, motion.Direction[axis]+direction[axis]))
+	}
+}
@@ -0,0 +1,73 @@
+package game
+
+import "valley-of-survival-dawn-of-squares/internal/definitions"
+
+// SetParty records the party of the player, 0 removing them from their party.
+// Parties are kept by the party package, the world only needs to know who
+// plays together. The caller must hold the world lock.
+func (w *World) SetParty(playerID uint, partyID uint) {
+	if partyID == 0 {
+		delete(w.parties, playerID)
+	} else {
+		w.parties[playerID] = partyID
+	}
+}
+
+// partyAround returns the party members of the player standing within the
+// share radius, downed ones excluded, the player not included.
+func (w *World) partyAround(player EntityID) []EntityID {
+	profile, ok := w.Players.Get(player)
+	if !ok || w.parties[profile.ID] == 0 {
+		return nil
+	}
+	party := w.parties[profile.ID]
+
+	transform, _ := w.Transforms.Get(player)
+	radius := int(definitions.Current().Tuning.Party.ShareRadius)
+	var members []EntityID
+	w.Players.Each(func(other EntityID, otherProfile *Player) {
+		if other == player || w.parties[otherProfile.ID] != party || w.IsDowned(other) {
+			return
+		}
+		otherTransform, _ := w.Transforms.Get(other)
+		dx := otherTransform.Position[0] - transform.Position[0]
+		dy := otherTransform.Position[1] - transform.Position[1]
+		if dx*dx+dy*dy <= radius*radius {
+			members = append(members, other)
+		}
+	})
+	return members
+}
+
+// shareExp gives the EXP of a gem the player collected, split evenly with the
+// party members around them. The gem is worth the share bonus more for every
+// member it is split with and the collector keeps what does not split evenly.
+// Every part is then scaled by the EXP reward of its receiver's run mode.
+func (w *World) shareExp(player EntityID, amount uint) []ExpShare {
+	profile, _ := w.Players.Get(player)
+	members := w.partyAround(player)
+	if len(members) == 0 {
+		profile.EXP += w.scaleExp(player, amount)
+		return nil
+	}
+
+	count := uint(len(members)) + 1
+	total := amount * (100 + definitions.Current().Tuning.Party.ShareBonus*(count-1)) / 100
+	share := total / count
+	profile.EXP += w.scaleExp(player, total-share*(count-1))
+
+	shares := make([]ExpShare, 0, len(members))
+	for _, member := range members {
+		memberProfile, _ := w.Players.Get(member)
+		gained := w.scaleExp(member, share)
+		memberProfile.EXP += gained
+		shares = append(shares, ExpShare{PlayerID: memberProfile.ID, Amount: gained, EXP: memberProfile.EXP})
+	}
+	return shares
+}
+
+// scaleExp returns the EXP the player gains from the amount with the reward of
+// their run mode, rounded to the nearest point.
+func (w *World) scaleExp(player EntityID, amount uint) uint {
+	return scaleExp(amount, w.runModifiers(player).Exp)
+}
@@ -0,0 +1,223 @@
+package game
+
+import (
+	"math"
+	"math/rand/v2"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// dropLoot rolls the loot of a killed enemy and spawns the pickups won, spread
+// over the square the enemy covered.
+func (w *World) dropLoot(id EntityID) []Pickup {
+	ai, ok := w.AIs.Get(id)
+	if !ok {
+		return nil
+	}
+	definition, ok := definitions.Current().Archetype(ai.Archetype)
+	if !ok {
+		return nil
+	}
+	transform, ok := w.Transforms.Get(id)
+	if !ok {
+		return nil
+	}
+
+	var killer uint
+	if health, ok := w.Healths.Get(id); ok {
+		killer = health.LastHitBy
+	}
+
+	var pickups []Pickup
+	for _, loot := range definition.Loot {
+		if rand.Float64() >= w.lootChance(killer, loot.Chance) {
+			continue
+		}
+
+		position := transform.Position
+		if len(pickups) != 0 {
+			for axis := range 2 {
+				offset := rand.IntN(int(definition.Size)+1) - int(definition.Size/2)
+				position[axis] = w.clamp(position[axis]+offset, 1)
+			}
+		}
+		pickups = append(pickups, w.spawnPickup(position, loot))
+	}
+	return pickups
+}
+
+func (w *World) spawnPickup(position [2]int, loot definitions.Loot) Pickup {
+	tuning := definitions.Current().Tuning.Pickups
+
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Collectibles.Add(id, Collectible{
+		Kind:     loot.Kind,
+		Amount:   loot.Amount,
+		Duration: loot.Duration,
+		Lifetime: tuning.Lifetime,
+		Position: [2]float64{float64(position[0]), float64(position[1])},
+	})
+
+	return Pickup{
+		ID:       id,
+		Kind:     loot.Kind,
+		Amount:   loot.Amount,
+		Position: position,
+		Lifetime: tuning.Lifetime,
+		Size:     tuning.Size,
+	}
+}
+
+// updatePickups pulls the pickups attracted by a magnet, despawns the expired
+// ones and lets the players collect the pickups they touch.
+func updatePickups(w *World, tick uint64) {
+	if w.Collectibles.Len() == 0 {
+		return
+	}
+
+	tuning := definitions.Current().Tuning.Pickups
+	var players []EntityID
+	for _, id := range w.Players.IDs() {
+		if !w.IsDowned(id) {
+			players = append(players, id)
+		}
+	}
+
+	var moved []MovedEntity
+	var despawned []uint
+	var collected []PickupCollect
+	var removed []EntityID
+
+	w.Collectibles.Each(func(id EntityID, collectible *Collectible) {
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			return
+		}
+
+		if collectible.Target != 0 && !w.Players.Has(collectible.Target) {
+			collectible.Target = 0
+		}
+
+		if collectible.Target != 0 {
+			target, _ := w.Transforms.Get(collectible.Target)
+			dx := float64(target.Position[0]) - collectible.Position[0]
+			dy := float64(target.Position[1]) - collectible.Position[1]
+			distance := math.Hypot(dx, dy)
+			step := min(distance, tuning.MagnetSpeed)
+			if distance > 0 {
+				collectible.Position[0] += dx / distance * step
+				collectible.Position[1] += dy / distance * step
+			}
+
+			transform.Position = [2]int{int(math.Round(collectible.Position[0])), int(math.Round(collectible.Position[1]))}
+			moved = append(moved, MovedEntity{ID: id, Position: transform.Position})
+		} else {
+			if collectible.Lifetime == 0 {
+				despawned = append(despawned, id)
+				removed = append(removed, id)
+				return
+			}
+			collectible.Lifetime--
+		}
+
+		box := newAABB(transform.Position, tuning.Size)
+		for _, player := range players {
+			if box.overlaps(w.box(player).grow(w.pickupRadius(player))) {
+				collected = append(collected, w.collect(player, id, collectible))
+				removed = append(removed, id)
+				return
+			}
+		}
+	})
+
+	for _, id := range removed {
+		w.RemoveEntity(id)
+	}
+
+	if len(moved) != 0 {
+		w.Emit(ServerMovePickups, moved)
+	}
+	if len(collected) != 0 {
+		w.Emit(ServerPickupsCollect, collected)
+	}
+	if len(despawned) != 0 {
+		w.Emit(ServerPickupsDespawn, despawned)
+	}
+}
+
+// collect applies the pickup to the player's entity.
+func (w *World) collect(player EntityID, id EntityID, collectible *Collectible) PickupCollect {
+	profile, _ := w.Players.Get(player)
+
+	var shared []ExpShare
+	switch collectible.Kind {
+	case definitions.LootExp:
+		shared = w.shareExp(player, collectible.Amount)
+	case definitions.LootHealth:
+		if health, ok := w.Healths.Get(player); ok && health.HP != 0 && !w.runModifiers(player).NoHealing {
+			health.HP = min(health.HP+collectible.Amount, health.MaxHP)
+		}
+	case definitions.LootMagnet:
+		w.Collectibles.Each(func(_ EntityID, other *Collectible) {
+			if other.Kind == definitions.LootExp {
+				other.Target = player
+			}
+		})
+	case definitions.LootHaste, definitions.LootFury:
+		w.addBuff(player, Buff{Kind: collectible.Kind, Amount: collectible.Amount, Ticks: collectible.Duration})
+	case definitions.LootChest:
+		if progression, ok := w.Progressions.Get(player); ok {
+			progression.Chests++
+		}
+	}
+
+	state := w.PlayerState(player)
+	return PickupCollect{
+		ID:       id,
+		PlayerID: profile.ID,
+		Kind:     collectible.Kind,
+		Amount:   collectible.Amount,
+		Duration: collectible.Duration,
+		HP:       state.HP,
+		EXP:      state.EXP,
+		Shared:   shared,
+	}
+}
+
+// addBuff gives the entity the buff, a buff of the same kind already active is
+// refreshed to the stronger and longer of both.
+func (w *World) addBuff(id EntityID, buff Buff) {
+	buffs, ok := w.Buffs.Get(id)
+	if !ok {
+		buffs = w.Buffs.Add(id, Buffs{})
+	}
+
+	for i := range buffs.Active {
+		active := &buffs.Active[i]
+		if active.Kind == buff.Kind {
+			active.Amount = max(active.Amount, buff.Amount)
+			active.Ticks = max(active.Ticks, buff.Ticks)
+			w.refreshModifiers(id)
+			return
+		}
+	}
+	buffs.Active = append(buffs.Active, buff)
+	w.refreshModifiers(id)
+}
+
+// updateBuffs counts the active buffs down and removes the expired ones.
+func updateBuffs(w *World, tick uint64) {
+	w.Buffs.Each(func(id EntityID, buffs *Buffs) {
+		active := buffs.Active[:0]
+		for _, buff := range buffs.Active {
+			if buff.Ticks > 1 {
+				buff.Ticks--
+				active = append(active, buff)
+			}
+		}
+		if len(active) != len(buffs.Active) {
+			buffs.Active = active
+			w.refreshModifiers(id)
+		}
+	})
+}
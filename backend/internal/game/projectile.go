@@ -0,0 +1,307 @@
+package game
+
+import (
+	"log"
+	"math"
+	"sort"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// Ballistics is the component of projectiles. The exact position is kept as
+// floats so slow or diagonal projectiles do not drift when rounded.
+type Ballistics struct {
+	Position [2]float64
+	Velocity [2]float64
+	Lifetime uint // ticks left
+	Size     uint
+	Damage   uint
+	Pierce   uint
+	AoE      float64    // radius of the explosion when it despawns, in world units
+	Hit      []EntityID // enemies already hit, never hit twice
+}
+
+// weaponRange converts a weapon range in player sizes to world units.
+func weaponRange(weapon *EquippedWeapon) float64 {
+	return float64(weapon.Range) * PlayerSize
+}
+
+// explode damages the enemies whose center is within radius of the position
+// on behalf of the player, except the ones in spared.
+func (w *World) explode(playerID uint, position [2]float64, radius float64, damage uint, spared []EntityID) []uint {
+	var damaged []uint
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		transform, ok := w.Transforms.Get(id)
+		if !ok || containsEntity(spared, id) {
+			return
+		}
+
+		dx := float64(transform.Position[0]) - position[0]
+		dy := float64(transform.Position[1]) - position[1]
+		if dx*dx+dy*dy <= radius*radius && w.hit(playerID, id, damage) {
+			damaged = append(damaged, id)
+		}
+	})
+	return damaged
+}
+
+// fireProjectiles fires a shot of the weapon from the entity towards target.
+func (w *World) fireProjectiles(owner EntityID, weapon *EquippedWeapon, target [2]int) []Projectile {
+	transform, _ := w.Transforms.Get(owner)
+	spec, ok := definitions.Current().Projectile(weapon.Projectile)
+	if !ok {
+		log.Printf("Weapon class %d fires unknown projectile %q", weapon.WeaponClassID, weapon.Projectile)
+		return nil
+	}
+
+	origin := [2]float64{float64(transform.Position[0]), float64(transform.Position[1])}
+	angle := math.Atan2(float64(target[1])-origin[1], float64(target[0])-origin[0])
+	lifetime := uint(math.Ceil(weaponRange(weapon) / spec.Speed))
+	damage := max(1, w.weaponDamage(owner, weapon)/max(1, spec.Pellets))
+
+	var ownerID uint
+	if o, ok := w.Owners.Get(owner); ok {
+		ownerID = o.PlayerID
+	}
+
+	projectiles := make([]Projectile, 0, spec.Pellets)
+	for i := range spec.Pellets {
+		pelletAngle := angle
+		if spec.Pellets > 1 {
+			pelletAngle += spec.Spread * math.Pi / 180 * (float64(i)/float64(spec.Pellets-1) - 0.5)
+		}
+		velocity := [2]float64{math.Cos(pelletAngle) * spec.Speed, math.Sin(pelletAngle) * spec.Speed}
+
+		id := w.NewEntity()
+		w.Transforms.Add(id, Transform{Position: transform.Position})
+		w.Owners.Add(id, Owner{PlayerID: ownerID})
+		w.Projectiles.Add(id, Ballistics{
+			Position: origin,
+			Velocity: velocity,
+			Lifetime: lifetime,
+			Size:     spec.Size,
+			Damage:   damage,
+			Pierce:   spec.Pierce,
+			AoE:      w.weaponArea(owner, weapon),
+		})
+
+		projectiles = append(projectiles, Projectile{
+			ID:       id,
+			OwnerID:  ownerID,
+			Position: transform.Position,
+			Velocity: velocity,
+			Lifetime: lifetime,
+			Size:     spec.Size,
+		})
+	}
+	return projectiles
+}
+
+// closestEnemy returns the enemy closest to the position within maxDistance
+// world units, 0 if there is none.
+func (w *World) closestEnemy(position [2]int, maxDistance float64) EntityID {
+	var closest EntityID
+	closestDistance := maxDistance * maxDistance
+
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			return
+		}
+
+		dx := float64(transform.Position[0]) - float64(position[0])
+		dy := float64(transform.Position[1]) - float64(position[1])
+		if distance := dx*dx + dy*dy; distance <= closestDistance {
+			closest, closestDistance = id, distance
+		}
+	})
+
+	return closest
+}
+
+// updateWeapons makes the players' weapons attack the closest enemy in range,
+// ranged weapons firing projectiles and melee weapons hitting directly.
+func updateWeapons(w *World, tick uint64) {
+	var fired []Projectile
+	var damagedEnemies []uint
+
+	w.Players.Each(func(id EntityID, player *Player) {
+		armament, ok := w.Weapons.Get(id)
+		if !ok || w.IsDowned(id) {
+			return
+		}
+		transform, ok := w.Transforms.Get(id)
+		if !ok {
+			return
+		}
+
+		for i := range armament.Weapons {
+			weapon := &armament.Weapons[i]
+			if weapon.Cooldown != 0 {
+				continue
+			}
+
+			reach := weaponRange(weapon)
+			if weapon.IsMelee() {
+				reach += PlayerSize
+			}
+			target := w.closestEnemy(transform.Position, reach)
+			if target == 0 || !weapon.ready(w.weaponCooldown(id, weapon)) {
+				continue
+			}
+
+			targetTransform, _ := w.Transforms.Get(target)
+			if weapon.IsMelee() {
+				damage := w.weaponDamage(id, weapon)
+				if w.hit(player.ID, target, damage) {
+					damagedEnemies = append(damagedEnemies, target)
+				}
+				if weapon.AoE > 0 {
+					center := [2]float64{float64(targetTransform.Position[0]), float64(targetTransform.Position[1])}
+					damagedEnemies = append(damagedEnemies, w.explode(player.ID, center, w.weaponArea(id, weapon), damage, []EntityID{target})...)
+				}
+				continue
+			}
+			fired = append(fired, w.fireProjectiles(id, weapon, targetTransform.Position)...)
+		}
+	})
+
+	if len(fired) != 0 {
+		w.Emit(ServerProjectilesSpawn, fired)
+	}
+	if len(damagedEnemies) != 0 {
+		w.Emit(ServerDamageEnemies, damagedEnemies)
+	}
+}
+
+// projectileSteps is the most a projectile moves between two hit tests, so
+// fast projectiles cannot skip over small enemies.
+const projectileSteps = 4.0
+
+// updateProjectiles moves the projectiles and hits the enemies in their way.
+// Projectiles despawn when their lifetime or pierce runs out, or when they
+// hit an obstacle or leave the world.
+func updateProjectiles(w *World, tick uint64) {
+	if w.Projectiles.Len() == 0 {
+		return
+	}
+
+	hash := w.buildSpatialHash()
+	var despawned []uint
+	var damagedEnemies []uint
+
+	w.Projectiles.Each(func(id EntityID, ballistics *Ballistics) {
+		var ownerID uint
+		if owner, ok := w.Owners.Get(id); ok {
+			ownerID = owner.PlayerID
+		}
+
+		// Projectiles explode wherever they stop, unless they left the world
+		despawn := func(explode bool) {
+			despawned = append(despawned, id)
+			if explode && ballistics.AoE > 0 {
+				damagedEnemies = append(damagedEnemies, w.explode(ownerID, ballistics.Position, ballistics.AoE, ballistics.Damage, ballistics.Hit)...)
+			}
+		}
+
+		if ballistics.Lifetime == 0 {
+			despawn(true)
+			return
+		}
+		ballistics.Lifetime--
+
+		speed := math.Hypot(ballistics.Velocity[0], ballistics.Velocity[1])
+		steps := max(1, int(math.Ceil(speed/projectileSteps)))
+		for range steps {
+			for axis := range 2 {
+				ballistics.Position[axis] += ballistics.Velocity[axis] / float64(steps)
+			}
+
+			x, y := ballistics.Position[0], ballistics.Position[1]
+			if !w.infinite && (x < 0 || y < 0 || x >= WorldSize || y >= WorldSize) {
+				despawn(false)
+				return
+			}
+
+			position := [2]int{int(math.Round(x)), int(math.Round(y))}
+			box := newAABB(position, ballistics.Size)
+			for _, other := range hash.query(box) {
+				if !box.overlaps(w.box(other)) {
+					continue
+				}
+
+				if collider, _ := w.Colliders.Get(other); collider.Static {
+					despawn(true)
+					return
+				}
+				if !w.AIs.Has(other) || containsEntity(ballistics.Hit, other) {
+					continue
+				}
+				// Enemies killed this tick wait for the reaper, their corpses
+				// don't use up pierce
+				if health, ok := w.Healths.Get(other); ok && health.HP == 0 {
+					continue
+				}
+
+				ballistics.Hit = append(ballistics.Hit, other)
+				if w.hit(ownerID, other, ballistics.Damage) {
+					damagedEnemies = append(damagedEnemies, other)
+				}
+
+				if ballistics.Pierce == 0 {
+					despawn(true)
+					return
+				}
+				ballistics.Pierce--
+			}
+		}
+
+		transform, _ := w.Transforms.Get(id)
+		transform.Position = [2]int{int(math.Round(ballistics.Position[0])), int(math.Round(ballistics.Position[1]))}
+	})
+
+	for _, id := range despawned {
+		w.RemoveEntity(id)
+	}
+
+	if len(despawned) != 0 {
+		sort.Slice(despawned, func(i, j int) bool { return despawned[i] < despawned[j] })
+		w.Emit(ServerProjectilesDespawn, despawned)
+	}
+	if len(damagedEnemies) != 0 {
+		w.Emit(ServerDamageEnemies, damagedEnemies)
+	}
+}
+
+func containsEntity(ids []EntityID, id EntityID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// updateReaper removes the enemies killed during the tick and drops their
+// loot.
+func updateReaper(w *World, tick uint64) {
+	var dead []uint
+	w.AIs.Each(func(id EntityID, _ *AI) {
+		if health, ok := w.Healths.Get(id); ok && health.HP == 0 {
+			dead = append(dead, id)
+		}
+	})
+
+	var dropped []Pickup
+	for _, id := range dead {
+		w.creditKill(id)
+		dropped = append(dropped, w.dropLoot(id)...)
+		w.RemoveEntity(id)
+	}
+
+	if len(dead) != 0 {
+		w.Emit(ServerEnemiesDespawn, dead)
+	}
+	if len(dropped) != 0 {
+		w.Emit(ServerPickupsSpawn, dropped)
+	}
+}
@@ -0,0 +1,71 @@
+package game
+
+import "testing"
+
+// addTestEnemy adds an enemy of the given HP centered on the position.
+func addTestEnemy(w *World, position [2]int, hp uint) EntityID {
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Colliders.Add(id, Collider{Size: 10})
+	w.Healths.Add(id, Health{HP: hp, MaxHP: 10})
+	w.AIs.Add(id, AI{})
+	return id
+}
+
+// addTestProjectile adds a projectile at the position moving right by speed
+// world units per tick.
+func addTestProjectile(w *World, position [2]int, speed float64, pierce uint) EntityID {
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Projectiles.Add(id, Ballistics{
+		Position: [2]float64{float64(position[0]), float64(position[1])},
+		Velocity: [2]float64{speed, 0},
+		Lifetime: 10,
+		Size:     2,
+		Damage:   5,
+		Pierce:   pierce,
+	})
+	return id
+}
+
+func TestProjectileHits(t *testing.T) {
+	tests := []struct {
+		name   string
+		hps    []uint // of the enemies in the way, in order
+		pierce uint
+		want   []uint // HPs of the enemies after the tick
+		alive  bool   // whether the projectile is still flying
+	}{
+		{"hits", []uint{10}, 0, []uint{5}, false},
+		{"pierces", []uint{10, 10}, 1, []uint{5, 5}, false},
+		{"stops at the first without pierce", []uint{10, 10}, 0, []uint{5, 10}, false},
+		{"flies through corpses", []uint{0, 10}, 0, []uint{0, 5}, false},
+		{"corpses keep pierce", []uint{0, 0, 10}, 1, []uint{0, 0, 5}, true},
+		{"only corpses", []uint{0, 0}, 0, []uint{0, 0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorld()
+			center := WorldSize / 2
+
+			var enemies []EntityID
+			for i, hp := range tt.hps {
+				enemies = append(enemies, addTestEnemy(w, [2]int{center + 20 + 20*i, center}, hp))
+			}
+			projectile := addTestProjectile(w, [2]int{center, center}, 100, tt.pierce)
+
+			updateProjectiles(w, 0)
+
+			for i, id := range enemies {
+				health, _ := w.Healths.Get(id)
+				if health.HP != tt.want[i] {
+					t.Errorf("enemy %d has %d HP, want %d", i, health.HP, tt.want[i])
+				}
+			}
+			if alive := w.Projectiles.Has(projectile); alive != tt.alive {
+				t.Errorf("projectile alive = %v, want %v", alive, tt.alive)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+type reloadRequest struct {
+	set    *definitions.Set
+	result chan error
+}
+
+var reloads = make(chan reloadRequest)
+
+// ApplyDefinitions hands the set to the running world, which swaps it in
+// between two ticks and updates the entities to the new stats. The current
+// definitions are kept if the set does not fit the world, such as when it
+// removes a weapon class or enemy in use.
+func ApplyDefinitions(ctx context.Context, set *definitions.Set) error {
+	request := reloadRequest{set: set, result: make(chan error, 1)}
+
+	select {
+	case reloads <- request:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func applyDefinitions(set *definitions.Set) error {
+	world.Lock()
+	defer world.Unlock()
+
+	if err := world.checkDefinitions(set); err != nil {
+		return err
+	}
+
+	definitions.SetCurrent(set)
+	setTickRate(set.Tuning.TickRate)
+	world.updateStats(set)
+	return nil
+}
+
+// checkDefinitions returns the entities the set has no definition for.
+func (w *World) checkDefinitions(set *definitions.Set) error {
+	var problems []error
+
+	w.Weapons.Each(func(id EntityID, armament *Armament) {
+		if !w.Players.Has(id) {
+			return
+		}
+		for _, weapon := range armament.Weapons {
+			if _, ok := set.Weapon(weapon.WeaponClassID); !ok {
+				problems = append(problems, fmt.Errorf("entity %d has weapon class %d", id, weapon.WeaponClassID))
+			}
+		}
+	})
+
+	w.Passives.Each(func(id EntityID, passives *Passives) {
+		for key := range passives.Levels {
+			if _, ok := set.Passive(key); !ok {
+				problems = append(problems, fmt.Errorf("entity %d has passive %q", id, key))
+			}
+		}
+	})
+
+	w.AIs.Each(func(id EntityID, ai *AI) {
+		if _, ok := set.Archetype(ai.Archetype); !ok {
+			problems = append(problems, fmt.Errorf("entity %d is enemy %q", id, ai.Archetype))
+		}
+	})
+
+	if len(problems) != 0 {
+		return fmt.Errorf("definitions missing for the world: %w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// updateStats gives the players and enemies the stats of the set, keeping
+// their levels, remaining cooldowns and the HP of the players, enemies keeping
+// their share of HP.
+func (w *World) updateStats(set *definitions.Set) {
+	player := set.Tuning.Player
+
+	w.Players.Each(func(id EntityID, _ *Player) {
+		w.refreshModifiers(id)
+		if motion, ok := w.Motions.Get(id); ok {
+			motion.Speed = player.Speed
+		}
+		if armament, ok := w.Weapons.Get(id); ok {
+			for i, weapon := range armament.Weapons {
+				definition, _ := set.Weapon(weapon.WeaponClassID)
+				armament.Weapons[i] = NewEquippedWeapon(definition, min(weapon.Level, definition.MaxLevel()))
+				armament.Weapons[i].WeaponID = weapon.WeaponID
+				armament.Weapons[i].EvolvedFrom = weapon.EvolvedFrom
+				armament.Weapons[i].Cooldown = min(weapon.Cooldown, definition.Cooldown)
+			}
+		}
+	})
+
+	w.AIs.Each(func(id EntityID, ai *AI) {
+		definition, _ := set.Archetype(ai.Archetype)
+
+		if health, ok := w.Healths.Get(id); ok && health.MaxHP != 0 {
+			maxHP := scaleEnemyHP(definition.HP, ai.HP)
+			health.HP = max(1, health.HP*maxHP/health.MaxHP)
+			health.MaxHP = maxHP
+		}
+		if motion, ok := w.Motions.Get(id); ok {
+			motion.Speed = definition.Speed
+		}
+		if collider, ok := w.Colliders.Get(id); ok {
+			collider.Size = definition.Size
+		}
+		if armament, ok := w.Weapons.Get(id); ok && len(armament.Weapons) != 0 {
+			weapon := &armament.Weapons[0]
+			weapon.Damage, weapon.Range, weapon.CooldownTicks = definition.Damage, definition.Range, definition.Cooldown
+			weapon.Cooldown = min(weapon.Cooldown, definition.Cooldown)
+		}
+	})
+
+	w.Bosses.Each(func(id EntityID, boss *Boss) {
+		ai, _ := w.AIs.Get(id)
+		if definition, ok := set.Boss(ai.Archetype); ok {
+			w.enterPhase(id, boss, definition, min(boss.Phase, len(definition.Phases)-1))
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+// updateSpawner spawns enemies around a random player every spawner interval,
+// as long as the world is below the enemy cap. The spawn rate of the player's
+// run mode sets how many, rates past a multiple of 100 percent giving a chance
+// of one more.
+func updateSpawner(w *World, tick uint64) {
+	set := definitions.Current()
+	spawner := set.Tuning.Spawner
+
+	if spawner.Interval == 0 || tick%uint64(spawner.Interval) != 0 {
+		return
+	}
+	if w.Players.Len() == 0 || uint(w.AIs.Len()) >= spawner.MaxEnemies {
+		return
+	}
+
+	players := w.Players.IDs()
+	player := players[rand.IntN(len(players))]
+	transform, ok := w.Transforms.Get(player)
+	if !ok {
+		return
+	}
+
+	modifiers := w.runModifiers(player)
+	count := min(spawnCount(modifiers.SpawnRate, rand.UintN(100)), spawner.MaxEnemies-uint(w.AIs.Len()))
+
+	for range count {
+		definition := pickEnemy(set, spawner.Weights)
+		if definition == nil {
+			return
+		}
+		w.SpawnEnemy(w.spawnPosition(transform.Position, definition.Size), definition, modifiers)
+	}
+}
+
+// spawnPosition returns a random position for an enemy of the given size,
+// between the spawner's min and max distance of around and out of the
+// obstacles and spawn areas when one is found.
+func (w *World) spawnPosition(around [2]int, size uint) [2]int {
+	spawner := definitions.Current().Tuning.Spawner
+
+	var position [2]int
+	for range placementAttempts {
+		angle := rand.Float64() * 2 * math.Pi
+		distance := float64(spawner.MinDistance) + rand.Float64()*float64(spawner.MaxDistance-spawner.MinDistance)
+
+		for axis, offset := range [2]float64{math.Cos(angle), math.Sin(angle)} {
+			position[axis] = w.clamp(around[axis]+int(offset*distance), size)
+		}
+		if !w.obstructed(position, size) && !w.inSpawnArea(position, size) {
+			break
+		}
+	}
+	return position
+}
+
+// pickEnemy returns an enemy drawn according to the weights, nil if they are
+// all zero.
+func pickEnemy(set *definitions.Set, weights map[string]uint) *definitions.Enemy {
+	var total uint
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	roll := rand.UintN(total)
+	for _, key := range keys {
+		if roll < weights[key] {
+			definition, _ := set.Enemy(key)
+			return definition
+		}
+		roll -= weights[key]
+	}
+	return nil
+}
@@ -1,18 +1,220 @@
 package game
 
-import "sync"
+import "valley-of-survival-dawn-of-squares/internal/definitions"
 
 const WorldSize = 2048
 const HalfWorldSize = 1024
 const PlayerSize = 32
 const HalfPlayerSize = 16
 
-type GameState struct {
-	SpawnedPlayersMu sync.RWMutex     `json:"-"`
-	SpawnedPlayers   map[uint]*Player `json:"players"`
+var world = NewWorld()
 
-	EnemiesMu sync.RWMutex    `json:"-"`
-	Enemies   map[uint]*Enemy `json:"enemies"`
+func GetWorld() *World {
+	return world
 }
 
-// var gameState GameState
+// PlayerSnapshot is everything about a player carried between the database
+// and the world.
+type PlayerSnapshot struct {
+	Player       Player
+	Weapons      []EquippedWeapon
+	Passives     map[string]uint // levels by key
+	UpgradeLevel uint            // see Progression
+	Offer        *PlayerUpgrade
+}
+
+// SpawnPlayer adds the player of the snapshot to the world, steered by the
+// client of the session, starting a new run of the mode with full HP if their
+// last one ended in death. The caller must hold the world lock.
+func (w *World) SpawnPlayer(sessionID string, snapshot PlayerSnapshot, mode RunMode) EntityID {
+	player := snapshot.Player
+	if id, ok := w.playerEntities[player.ID]; ok {
+		return id
+	}
+
+	id := w.NewEntity()
+	w.playerEntities[player.ID] = id
+	w.sessionEntities[sessionID] = id
+	tuning := definitions.Current().Tuning.Player
+
+	position := [2]int{w.clamp(player.Position[0], PlayerSize), w.clamp(player.Position[1], PlayerSize)}
+	if loaded := w.loadChunksAround(position); len(loaded) != 0 {
+		w.Emit(ServerMapChunksLoad, loaded)
+	}
+	position = w.freePosition(position, PlayerSize)
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Colliders.Add(id, Collider{Size: PlayerSize})
+	w.Owners.Add(id, Owner{PlayerID: player.ID})
+	w.Weapons.Add(id, Armament{Weapons: snapshot.Weapons})
+	w.Motions.Add(id, Motion{Speed: tuning.Speed})
+	w.Controls.Add(id, Controller{SessionID: sessionID})
+	w.Players.Add(id, player)
+	w.Runs.Add(id, Run{StartTick: CurrentTick(), StartEXP: player.EXP, Mode: mode})
+	w.Progressions.Add(id, Progression{Level: max(1, snapshot.UpgradeLevel), Offer: snapshot.Offer})
+
+	passives := make(map[string]uint, len(snapshot.Passives))
+	for key, level := range snapshot.Passives {
+		passives[key] = level
+	}
+	w.Passives.Add(id, Passives{Levels: passives})
+	w.refreshModifiers(id)
+
+	maxHP := tuning.MaxHP + uint(w.modifiers(id).get(definitions.StatMaxHP))
+	if player.HP == 0 {
+		player.HP = maxHP
+	}
+	w.Healths.Add(id, Health{HP: min(player.HP, maxHP), MaxHP: maxHP})
+
+	w.Emit(ServerPlayersSpawn, []Player{w.PlayerState(id)})
+	if snapshot.Offer != nil {
+		w.Emit(ServerUpgradePlayer, []PlayerUpgrade{*snapshot.Offer})
+	}
+	return id
+}
+
+// DespawnPlayer removes the player from the world and returns their last
+// state. Leaving while downed counts as dying, starting the respawn cooldown.
+// The caller must hold the world lock.
+func (w *World) DespawnPlayer(playerID uint) (PlayerSnapshot, bool) {
+	id, ok := w.playerEntities[playerID]
+	if !ok {
+		return PlayerSnapshot{}, false
+	}
+
+	snapshot := w.PlayerSnapshot(id)
+	if w.IsDowned(id) {
+		snapshot.Player.HP = 0
+		w.startRespawnCooldown(playerID)
+	}
+	w.RemoveEntity(id)
+
+	w.Emit(ServerPlayersDespawn, []uint{playerID})
+	return snapshot, true
+}
+
+// PlayerSnapshot returns the player of the entity with everything saved
+// about them.
+func (w *World) PlayerSnapshot(id EntityID) PlayerSnapshot {
+	snapshot := PlayerSnapshot{Player: w.PlayerState(id), Passives: make(map[string]uint)}
+	if armament, ok := w.Weapons.Get(id); ok {
+		snapshot.Weapons = append(snapshot.Weapons, armament.Weapons...)
+	}
+	if passives, ok := w.Passives.Get(id); ok {
+		for key, level := range passives.Levels {
+			snapshot.Passives[key] = level
+		}
+	}
+	if progression, ok := w.Progressions.Get(id); ok {
+		snapshot.UpgradeLevel, snapshot.Offer = progression.Level, progression.Offer
+	}
+	return snapshot
+}
+
+func (w *World) PlayerEntity(playerID uint) (EntityID, bool) {
+	id, ok := w.playerEntities[playerID]
+	return id, ok
+}
+
+// SessionEntity returns the entity steered by the client of the session.
+func (w *World) SessionEntity(sessionID string) (EntityID, bool) {
+	id, ok := w.sessionEntities[sessionID]
+	return id, ok
+}
+
+// PlayerState returns the player of the entity with its current position and HP.
+func (w *World) PlayerState(id EntityID) Player {
+	var player Player
+	if profile, ok := w.Players.Get(id); ok {
+		player = *profile
+	}
+	if transform, ok := w.Transforms.Get(id); ok {
+		player.Position = transform.Position
+	}
+	if health, ok := w.Healths.Get(id); ok {
+		player.HP = health.HP
+	}
+	return player
+}
+
+// SpawnEnemy adds an enemy of the definition chasing the closest player. The
+// caller must hold the world lock.
+func (w *World) SpawnEnemy(position [2]int, definition *definitions.Enemy, modifiers definitions.RunModifiers) EntityID {
+	id := w.NewEntity()
+	hp := scaleEnemyHP(definition.HP, modifiers.EnemyHP)
+
+	weapon := EquippedWeapon{
+		Damage:        definition.Damage,
+		Range:         definition.Range,
+		CooldownTicks: definition.Cooldown,
+	}
+
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Healths.Add(id, Health{HP: hp, MaxHP: hp})
+	w.Colliders.Add(id, Collider{Size: definition.Size})
+	w.Weapons.Add(id, Armament{Weapons: []EquippedWeapon{weapon}})
+	w.AIs.Add(id, AI{Kind: AIChase, Archetype: definition.Key, HP: modifiers.EnemyHP})
+	w.Motions.Add(id, Motion{Speed: definition.Speed})
+	w.Modifiers.Add(id, Modifiers{Stats: map[string]float64{
+		definitions.StatMoveSpeed: float64(modifiers.EnemySpeed) - 100,
+		definitions.StatDamage:    float64(modifiers.EnemyDamage) - 100,
+	}})
+
+	w.Emit(ServerEnemiesSpawn, []Enemy{w.EnemyState(id)})
+	return id
+}
+
+// SpawnBoss adds the boss of the definition in its first phase and shows its
+// health bar. The caller must hold the world lock.
+func (w *World) SpawnBoss(position [2]int, definition *definitions.Boss, modifiers definitions.RunModifiers) EntityID {
+	id := w.SpawnEnemy(position, &definition.Enemy, modifiers)
+	boss := w.Bosses.Add(id, Boss{HP: definition.HP, Cooldown: definition.Phases[0].Attack.Cooldown})
+	w.enterPhase(id, boss, definition, 0)
+
+	w.Emit(ServerBossHealth, []BossHealth{w.BossState(id)})
+	return id
+}
+
+// SpawnObstacle adds a static square blocking players and enemies. The caller
+// must hold the world lock.
+func (w *World) SpawnObstacle(position [2]int, size uint) EntityID {
+	id := w.NewEntity()
+
+	w.Transforms.Add(id, Transform{Position: position})
+	w.Colliders.Add(id, Collider{Size: size, Static: true})
+	w.invalidateFlowFields()
+
+	return id
+}
+
+// EnemyState builds the enemy sent to clients from the entity's components.
+func (w *World) EnemyState(id EntityID) Enemy {
+	enemy := Enemy{ID: id}
+	if ai, ok := w.AIs.Get(id); ok {
+		enemy.Kind = ai.Archetype
+		_, enemy.Boss = definitions.Current().Boss(ai.Archetype)
+	}
+	if transform, ok := w.Transforms.Get(id); ok {
+		enemy.Position = transform.Position
+	}
+	if collider, ok := w.Colliders.Get(id); ok {
+		enemy.Size = collider.Size
+	}
+	if armament, ok := w.Weapons.Get(id); ok && len(armament.Weapons) != 0 {
+		weapon := armament.Weapons[0]
+		enemy.Damage, enemy.Range, enemy.RateOfFire = weapon.Damage, weapon.Range, weapon.CooldownTicks
+	}
+	return enemy
+}
+
+// SpawnedPlayersSnapshot returns the current state of the spawned players,
+// safe to call while the world keeps running.
+func (w *World) SpawnedPlayersSnapshot() []PlayerSnapshot {
+	w.RLock()
+	defer w.RUnlock()
+
+	players := make([]PlayerSnapshot, 0, w.Players.Len())
+	w.Players.Each(func(id EntityID, _ *Player) {
+		players = append(players, w.PlayerSnapshot(id))
+	})
+	return players
+}
@@ -0,0 +1,141 @@
+package game
+
+import "valley-of-survival-dawn-of-squares/internal/definitions"
+
+// maxCooldownReduction caps the cooldown stat so weapons never fire every
+// tick.
+const maxCooldownReduction = 75
+
+// Passives holds the levels of the passive items of an entity, by key.
+type Passives struct {
+	Levels map[string]uint
+}
+
+// Modifiers are the stat bonuses of an entity, summed over its passives and
+// buffs. Every movement and weapon computation reads them through the
+// helpers below rather than the raw components.
+type Modifiers struct {
+	Stats map[string]float64 // by stat, in the unit of the stat
+}
+
+func (m Modifiers) get(stat string) float64 {
+	return m.Stats[stat]
+}
+
+// buffStats maps the kinds of buffs to the stat they raise.
+var buffStats = map[string]string{
+	definitions.LootHaste: definitions.StatMoveSpeed,
+	definitions.LootFury:  definitions.StatDamage,
+}
+
+func (w *World) modifiers(id EntityID) Modifiers {
+	if modifiers, ok := w.Modifiers.Get(id); ok {
+		return *modifiers
+	}
+	return Modifiers{}
+}
+
+// refreshModifiers recomputes the entity's modifiers from its passives and
+// buffs, and the max HP depending on them. It must be called whenever either
+// changes.
+func (w *World) refreshModifiers(id EntityID) {
+	set := definitions.Current()
+	stats := make(map[string]float64)
+
+	if passives, ok := w.Passives.Get(id); ok {
+		for key, level := range passives.Levels {
+			if passive, ok := set.Passive(key); ok {
+				stats[passive.Stat] += passive.Value * float64(level)
+			}
+		}
+	}
+	if buffs, ok := w.Buffs.Get(id); ok {
+		for _, buff := range buffs.Active {
+			stats[buffStats[buff.Kind]] += float64(buff.Amount)
+		}
+	}
+
+	w.Modifiers.Add(id, Modifiers{Stats: stats})
+
+	if w.Players.Has(id) {
+		if health, ok := w.Healths.Get(id); ok {
+			maxHP := set.Tuning.Player.MaxHP + uint(stats[definitions.StatMaxHP])
+			if maxHP > health.MaxHP && health.HP != 0 {
+				health.HP += maxHP - health.MaxHP
+			}
+			health.MaxHP = maxHP
+			health.HP = min(health.HP, health.MaxHP)
+		}
+	}
+}
+
+// percent applies a percentage bonus to a value.
+func percent(value uint, bonus float64) uint {
+	return uint(float64(value) * (100 + bonus) / 100)
+}
+
+// scaleExp returns the EXP gained from the amount with the EXP reward of a run
+// mode, in percent, rounded to the nearest point.
+func scaleExp(amount uint, reward uint) uint {
+	return (amount*reward + 50) / 100
+}
+
+// scaleEnemyHP returns the HP of an enemy of the base HP with the enemy HP of
+// a run mode, in percent, at least 1.
+func scaleEnemyHP(hp uint, modifier uint) uint {
+	return max(1, hp*modifier/100)
+}
+
+// spawnCount returns how many enemies a spawn of the rate, in percent, adds.
+// Every full hundred percent adds one and the rest is the chance of one more,
+// which roll, drawn below 100, decides.
+func spawnCount(rate uint, roll uint) uint {
+	count := rate / 100
+	if roll < rate%100 {
+		count++
+	}
+	return count
+}
+
+// moveSpeed returns the world units the entity moves per tick, slowed down by
+// the slowest zone it stands in. It may be a fraction of a unit, see Motion.
+func (w *World) moveSpeed(id EntityID, motion *Motion) float64 {
+	bonus := w.modifiers(id).get(definitions.StatMoveSpeed) - float64(w.terrainAmount(id, definitions.ZoneSlow))
+	return float64(motion.Speed) * (100 + max(-100, bonus)) / 100
+}
+
+// weaponDamage returns the damage of the entity's weapon.
+func (w *World) weaponDamage(id EntityID, weapon *EquippedWeapon) uint {
+	return percent(weapon.Damage, w.modifiers(id).get(definitions.StatDamage))
+}
+
+// weaponCooldown returns the ticks between two hits of the entity's weapon.
+func (w *World) weaponCooldown(id EntityID, weapon *EquippedWeapon) uint {
+	reduction := min(w.modifiers(id).get(definitions.StatCooldown), maxCooldownReduction)
+	return max(1, percent(weapon.CooldownTicks, -reduction))
+}
+
+// weaponArea returns the radius in world units of the weapon's area of effect.
+func (w *World) weaponArea(id EntityID, weapon *EquippedWeapon) float64 {
+	return float64(weapon.AoE) * PlayerSize * (100 + w.modifiers(id).get(definitions.StatArea)) / 100
+}
+
+// damageTaken returns what a hit of the given damage takes from the entity.
+func (w *World) damageTaken(id EntityID, damage uint) uint {
+	armor := uint(w.modifiers(id).get(definitions.StatArmor))
+	return max(1, damage-min(damage, armor))
+}
+
+// pickupRadius returns how far beyond its square the entity collects pickups.
+func (w *World) pickupRadius(id EntityID) int {
+	return int(w.modifiers(id).get(definitions.StatPickupRadius))
+}
+
+// lootChance returns the chance of a drop for an enemy killed by the player.
+func (w *World) lootChance(playerID uint, chance float64) float64 {
+	id, ok := w.playerEntities[playerID]
+	if !ok {
+		return chance
+	}
+	return min(1, chance*(100+w.modifiers(id).get(definitions.StatLuck))/100)
+}
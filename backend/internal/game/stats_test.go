@@ -0,0 +1,99 @@
+package game
+
+import "testing"
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		value uint
+		bonus float64
+		want  uint
+	}{
+		{100, 0, 100},
+		{100, 25, 125},
+		{100, -25, 75},
+		{7, 50, 10},
+		{10, -100, 0},
+	}
+
+	for _, tt := range tests {
+		if got := percent(tt.value, tt.bonus); got != tt.want {
+			t.Errorf("percent(%d, %v) = %d, want %d", tt.value, tt.bonus, got, tt.want)
+		}
+	}
+}
+
+func TestScaleExp(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount uint
+		reward uint
+		want   uint
+	}{
+		{"neutral", 10, 100, 10},
+		{"bonus", 10, 150, 15},
+		{"malus", 10, 50, 5},
+		{"rounds half up", 3, 150, 5},
+		{"rounds up to the nearest", 7, 110, 8},
+		{"rounds down to the nearest", 3, 110, 3},
+		{"small amounts are kept", 1, 60, 1},
+		{"small amounts may vanish", 1, 40, 0},
+		{"nothing", 0, 200, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scaleExp(tt.amount, tt.reward); got != tt.want {
+				t.Errorf("scaleExp(%d, %d) = %d, want %d", tt.amount, tt.reward, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleEnemyHP(t *testing.T) {
+	tests := []struct {
+		name     string
+		hp       uint
+		modifier uint
+		want     uint
+	}{
+		{"neutral", 40, 100, 40},
+		{"tougher", 40, 150, 60},
+		{"weaker", 40, 75, 30},
+		{"rounds down", 9, 150, 13},
+		{"at least 1", 1, 50, 1},
+		{"at least 1 without HP", 40, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scaleEnemyHP(tt.hp, tt.modifier); got != tt.want {
+				t.Errorf("scaleEnemyHP(%d, %d) = %d, want %d", tt.hp, tt.modifier, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpawnCount(t *testing.T) {
+	tests := []struct {
+		name string
+		rate uint
+		roll uint
+		want uint
+	}{
+		{"neutral", 100, 99, 1},
+		{"double", 200, 0, 2},
+		{"extra chance hit", 150, 49, 2},
+		{"extra chance missed", 150, 50, 1},
+		{"below one hit", 40, 39, 1},
+		{"below one missed", 40, 40, 0},
+		{"none", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spawnCount(tt.rate, tt.roll); got != tt.want {
+				t.Errorf("spawnCount(%d, %d) = %d, want %d", tt.rate, tt.roll, got, tt.want)
+			}
+		})
+	}
+}
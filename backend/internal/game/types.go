@@ -1,5 +1,48 @@
 package game
 
+import (
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+const (
+	ClanPolicyOpen       = "open"        // anyone can join by name
+	ClanPolicyClosed     = "closed"      // joining requires the clan password or an invite
+	ClanPolicyInviteOnly = "invite_only" // joining requires an invite
+)
+
+const (
+	ClanRoleOwner   = "owner"
+	ClanRoleOfficer = "officer"
+	ClanRoleMember  = "member"
+)
+
+const (
+	ClanActionJoin     = "join"
+	ClanActionLeave    = "leave"
+	ClanActionKick     = "kick"
+	ClanActionPromote  = "promote"
+	ClanActionDemote   = "demote"
+	ClanActionTransfer = "transfer"
+)
+
+const (
+	ChatChannelGlobal = "global"
+	ChatChannelClan   = "clan"
+	ChatChannelDirect = "direct"
+)
+
+const (
+	ChatIgnoreMute  = "mute"  // hides the target's global and clan messages
+	ChatIgnoreBlock = "block" // hides all of the target's messages and rejects their direct messages
+)
+
+const (
+	PresenceOffline = "offline"
+	PresenceOnline  = "online"  // connected to the websocket
+	PresenceSpawned = "spawned" // connected and spawned in the world
+)
+
 type (
 	User struct {
 		ID       uint   `json:"id"`
@@ -8,43 +51,223 @@ type (
 	}
 
 	Clan struct {
-		ID       uint   `json:"id"`
-		Name     string `json:"name"`
-		Password string `json:"-"`
-		OwnerID  uint   `json:"owner_id"`
+		ID         uint   `json:"id"`
+		Name       string `json:"name"`
+		Password   string `json:"-"`
+		OwnerID    uint   `json:"owner_id"`
+		JoinPolicy string `json:"join_policy"`
+	}
+
+	ClanInvite struct {
+		ID        uint       `json:"id"`
+		ClanID    uint       `json:"clan_id"`
+		Code      string     `json:"code"`
+		CreatedBy uint       `json:"created_by"`
+		UsesLeft  *uint      `json:"uses_left,omitempty"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}
+
+	ClanAuditEntry struct {
+		ID        uint      `json:"id"`
+		ClanID    uint      `json:"clan_id"`
+		ActorID   uint      `json:"actor_id"`
+		TargetID  *uint     `json:"target_id,omitempty"`
+		Action    string    `json:"action"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	ChatMessage struct {
+		ID          uint      `json:"id"`
+		Channel     string    `json:"channel"`
+		SenderID    uint      `json:"sender_id"`
+		Sender      string    `json:"sender"`
+		ClanID      *uint     `json:"clan_id,omitempty"`
+		RecipientID *uint     `json:"recipient_id,omitempty"`
+		Text        string    `json:"text"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+
+	ChatIgnore struct {
+		Username string `json:"username"`
+		Kind     string `json:"kind"`
+	}
+
+	Friend struct {
+		UserID   uint   `json:"user_id"`
+		Username string `json:"username"`
+		Presence string `json:"presence,omitempty"`
+	}
+
+	FriendList struct {
+		Friends  []*Friend `json:"friends"`
+		Incoming []*Friend `json:"incoming"` // pending requests sent to the user
+		Outgoing []*Friend `json:"outgoing"` // pending requests sent by the user
+	}
+
+	// Party is a short-lived group of players playing a run together, sent
+	// to its members whenever it changes.
+	Party struct {
+		ID      uint          `json:"id"`
+		Leader  string        `json:"leader"` // username of the member starting the run
+		Members []PartyMember `json:"members"`
+		Invited []string      `json:"invited"` // usernames with a pending invite
+	}
+
+	PartyMember struct {
+		PlayerID uint   `json:"player_id"`
+		Username string `json:"username"`
+		Presence string `json:"presence"`
+	}
+
+	// PartyInvite is sent to an invited user, who answers with
+	// ClientPartyAccept or ClientPartyDecline and the party ID.
+	PartyInvite struct {
+		PartyID uint   `json:"party_id"`
+		Leader  string `json:"leader"`
 	}
 
 	Player struct {
-		ID          uint    `json:"id"`
-		UserID      uint    `json:"user_id"`
-		HP          uint    `json:"hp"`
-		Position    [2]uint `json:"position"`
-		Color       string  `json:"color"`
-		Texturepath string  `json:"texture_path,omitempty"`
-		EXP         uint    `json:"exp"`
-		ClanID      *uint   `json:"clan_id,omitempty"`
+		ID          uint   `json:"id"`
+		UserID      uint   `json:"user_id"`
+		HP          uint   `json:"hp"`
+		Position    [2]int `json:"position"`
+		Color       string `json:"color"`
+		Texturepath string `json:"texture_path,omitempty"`
+		EXP         uint   `json:"exp"`
+		ClanID      *uint  `json:"clan_id,omitempty"`
+		ClanRole    string `json:"clan_role,omitempty"`
 	}
 
 	WeaponClass struct {
 		ID             uint    `json:"id"`
+		Name           string  `json:"name"`
 		BaseDamage     uint    `json:"base_damage"`
 		BaseRange      float32 `json:"base_range"`
-		BaseRateOfFire uint    `json:"base_rate_of_fire"`
+		BaseRateOfFire uint    `json:"base_rate_of_fire"` // ticks between two hits
 	}
 
 	Weapon struct {
-		ID            uint `json:"id"`
-		WeaponClassID uint `json:"weapon_class_id"`
-		Level         uint `json:"level"`
-		PlayerID      uint `json:"player_id"`
+		ID            uint  `json:"id"`
+		WeaponClassID uint  `json:"weapon_class_id"`
+		Level         uint  `json:"level"`
+		PlayerID      uint  `json:"player_id"`
+		EvolvedFrom   *uint `json:"evolved_from,omitempty"` // weapon class it evolved from
 	}
 
 	Enemy struct {
 		ID         uint    `json:"id"`
-		Position   [2]uint `json:"position"`
+		Kind       string  `json:"kind"` // key of the enemy or boss definition
+		Position   [2]int  `json:"position"`
 		Damage     uint    `json:"-"`
 		Range      float32 `json:"-"`
 		RateOfFire uint    `json:"-"`
 		Size       uint    `json:"size"`
+		Boss       bool    `json:"boss,omitempty"`
+	}
+
+	// BossHealth fills the boss health bar shown to every player.
+	BossHealth struct {
+		ID    uint   `json:"id"`
+		Kind  string `json:"kind"` // key of the boss definition
+		Name  string `json:"name"`
+		HP    uint   `json:"hp"`
+		MaxHP uint   `json:"max_hp"`
+		Phase int    `json:"phase"` // index of the phase, from 0
+	}
+
+	// Telegraph warns of a boss area attack hitting the circle in Ticks ticks.
+	Telegraph struct {
+		ID       uint    `json:"id"`
+		BossID   uint    `json:"boss_id"`
+		Position [2]int  `json:"position"`
+		Radius   float64 `json:"radius"` // world units
+		Damage   uint    `json:"damage"`
+		Ticks    uint    `json:"ticks"`
+	}
+
+	// Projectile is sent once when fired, clients move it by Velocity every
+	// tick until it despawns or its lifetime runs out.
+	Projectile struct {
+		ID       uint       `json:"id"`
+		OwnerID  uint       `json:"owner_id"` // ID of the player who fired it
+		Position [2]int     `json:"position"`
+		Velocity [2]float64 `json:"velocity"` // world units per tick
+		Lifetime uint       `json:"lifetime"` // ticks
+		Size     uint       `json:"size"`
+	}
+
+	Pickup struct {
+		ID       uint   `json:"id"`
+		Kind     string `json:"kind"` // kind of loot
+		Amount   uint   `json:"amount"`
+		Position [2]int `json:"position"`
+		Lifetime uint   `json:"lifetime"` // ticks before it despawns
+		Size     uint   `json:"size"`
+	}
+
+	// DownedPlayer is a player whose HP reached zero, dying for good after
+	// BleedOut ticks unless an ally revives them.
+	DownedPlayer struct {
+		ID       uint `json:"id"`
+		BleedOut uint `json:"bleed_out"`
+	}
+
+	// RunMode is the difficulty and curses a player picked for their run,
+	// sent with ClientPlayerSpawn.
+	RunMode struct {
+		Difficulty string   `json:"difficulty"` // key of the difficulty, the default one if empty
+		Curses     []string `json:"curses"`     // keys of the curses
+	}
+
+	// RunSummary sums up the run a player's final death ended.
+	RunSummary struct {
+		PlayerID uint    `json:"player_id"`
+		Ticks    uint64  `json:"ticks"` // survived
+		Seconds  float64 `json:"seconds"`
+		Kills    uint    `json:"kills"`
+		EXP      uint    `json:"exp"` // gained during the run
+		Level    uint    `json:"level"`
+		Seed     uint64  `json:"seed"`             // of the map, 0 for a loaded layout
+		Layout   string  `json:"layout,omitempty"` // file the map was loaded from
+		RunMode
+	}
+
+	// MapLayout is the static content of the world, sent once to every
+	// client when it connects.
+	MapLayout struct {
+		Seed           uint64 `json:"seed"`                 // 0 for a loaded layout
+		Name           string `json:"name,omitempty"`       // file of a loaded layout
+		Size           uint   `json:"size"`                 // of the square world, 0 for infinite worlds
+		Infinite       bool   `json:"infinite"`             // areas come in chunks, see MapChunk
+		ChunkSize      uint   `json:"chunk_size,omitempty"` // of the chunks of an infinite world
+		DamageInterval uint   `json:"damage_interval"`      // ticks between two hits of damage zones
+		definitions.Layout
+	}
+
+	// MapChunk is a square of an infinite world with its obstacles and zones,
+	// covering ChunkSize units from Chunk times ChunkSize.
+	MapChunk struct {
+		Chunk [2]int `json:"chunk"`
+		definitions.Layout
+	}
+
+	// PickupCollect tells which player collected a pickup and the player's HP
+	// and EXP after collecting it.
+	PickupCollect struct {
+		ID       uint       `json:"id"`
+		PlayerID uint       `json:"player_id"`
+		Kind     string     `json:"kind"`
+		Amount   uint       `json:"amount"`
+		Duration uint       `json:"duration,omitempty"` // ticks, for buffs
+		HP       uint       `json:"hp"`
+		EXP      uint       `json:"exp"`
+		Shared   []ExpShare `json:"shared,omitempty"` // EXP given to the collector's party members
+	}
+
+	// ExpShare is the part of a collected EXP gem a party member received.
+	ExpShare struct {
+		PlayerID uint `json:"player_id"`
+		Amount   uint `json:"amount"`
+		EXP      uint `json:"exp"` // of the member once shared
 	}
 )
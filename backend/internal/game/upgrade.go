@@ -0,0 +1,232 @@
+package game
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// Kinds of upgrade choices.
+const (
+	UpgradeWeapon    = "weapon"
+	UpgradePassive   = "passive"
+	UpgradeEvolution = "evolution"
+)
+
+// Progression tracks the level-up offers of a player. Level is the last level
+// an offer was made for, the player is owed one offer for every level their
+// EXP reached beyond it and for every chest they collected.
+type Progression struct {
+	Level  uint
+	Chests uint           // collected and not offered yet
+	Offer  *PlayerUpgrade // waiting for the player's choice, nil if none
+}
+
+// evolutionChoices returns the evolutions of the entity's weapons at their max
+// level whose recipe passive it owns.
+func (w *World) evolutionChoices(id EntityID) []UpgradeChoice {
+	set := definitions.Current()
+
+	armament, ok := w.Weapons.Get(id)
+	if !ok {
+		return nil
+	}
+	passives, ok := w.Passives.Get(id)
+	if !ok {
+		return nil
+	}
+
+	var choices []UpgradeChoice
+	for _, weapon := range armament.Weapons {
+		definition, ok := set.Weapon(weapon.WeaponClassID)
+		if !ok || weapon.Level < definition.MaxLevel() {
+			continue
+		}
+		evolution, into, ok := set.Evolution(weapon.WeaponClassID)
+		if !ok || passives.Levels[evolution.Passive] == 0 {
+			continue
+		}
+		choices = append(choices, UpgradeChoice{Kind: UpgradeEvolution, WeaponClassID: into.ID, EvolvesFrom: weapon.WeaponClassID, Level: 1})
+	}
+	return choices
+}
+
+// upgradeChoices draws the choices of an offer. Evolutions ready to be made
+// get an offer of their own, otherwise the choices are drawn among leveling up
+// an owned weapon or passive and, while the player has free slots, new ones.
+func (w *World) upgradeChoices(id EntityID) []UpgradeChoice {
+	set := definitions.Current()
+	tuning := set.Tuning.Upgrades
+
+	if evolutions := w.evolutionChoices(id); len(evolutions) != 0 {
+		return evolutions[:min(len(evolutions), int(tuning.Choices))]
+	}
+
+	weapons := make(map[uint]uint)
+	if armament, ok := w.Weapons.Get(id); ok {
+		for _, weapon := range armament.Weapons {
+			weapons[weapon.WeaponClassID] = weapon.Level
+		}
+	}
+	passives := make(map[string]uint)
+	if owned, ok := w.Passives.Get(id); ok {
+		passives = owned.Levels
+	}
+
+	var candidates []UpgradeChoice
+	for _, weapon := range set.Weapons {
+		if _, evolved := set.EvolvedFrom(weapon.ID); evolved {
+			continue
+		}
+		if _, into, ok := set.Evolution(weapon.ID); ok {
+			if _, owned := weapons[into.ID]; owned {
+				continue
+			}
+		}
+
+		level, owned := weapons[weapon.ID]
+		if (owned && level < weapon.MaxLevel()) || (!owned && uint(len(weapons)) < tuning.MaxWeapons) {
+			candidates = append(candidates, UpgradeChoice{Kind: UpgradeWeapon, WeaponClassID: weapon.ID, Level: level + 1})
+		}
+	}
+	for _, passive := range set.Passives {
+		level, owned := passives[passive.Key]
+		if (owned && level < passive.MaxLevel) || (!owned && uint(len(passives)) < tuning.MaxPassives) {
+			candidates = append(candidates, UpgradeChoice{Kind: UpgradePassive, Passive: passive.Key, Level: level + 1})
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates[:min(len(candidates), int(tuning.Choices))]
+}
+
+// updateUpgrades makes an offer to the players owed one and without an offer
+// waiting for their choice, levels coming before chests.
+func updateUpgrades(w *World, tick uint64) {
+	curve := definitions.Current().Tuning.ExpCurve
+
+	var offers []PlayerUpgrade
+	w.Progressions.Each(func(id EntityID, progression *Progression) {
+		player, ok := w.Players.Get(id)
+		if !ok || progression.Offer != nil || w.IsDowned(id) {
+			return
+		}
+
+		for progression.Level < curve.LevelForExp(player.EXP) || progression.Chests != 0 {
+			if progression.Level < curve.LevelForExp(player.EXP) {
+				progression.Level++
+			} else {
+				progression.Chests--
+			}
+
+			choices := w.upgradeChoices(id)
+			if len(choices) == 0 {
+				continue
+			}
+
+			progression.Offer = &PlayerUpgrade{ID: player.ID, Level: progression.Level, Choices: choices}
+			offers = append(offers, *progression.Offer)
+			return
+		}
+	})
+
+	if len(offers) != 0 {
+		w.Emit(ServerUpgradePlayer, offers)
+	}
+}
+
+// handleSelect applies the choice of the offer waiting for the client's
+// player, numbered from 1.
+func handleSelect(w *World, message ws.Message) {
+	var choice int
+	switch data := message.Data.(type) {
+	case string:
+		choice, _ = strconv.Atoi(data)
+	case float64:
+		choice = int(data)
+	}
+
+	id, ok := w.SessionEntity(message.SessionID)
+	if !ok {
+		return
+	}
+	progression, ok := w.Progressions.Get(id)
+	if !ok || progression.Offer == nil || choice < 1 || choice > len(progression.Offer.Choices) {
+		return
+	}
+
+	selected := progression.Offer.Choices[choice-1]
+	progression.Offer = nil
+	if w.applyUpgrade(id, selected) {
+		player, _ := w.Players.Get(id)
+		w.Emit(ServerUpgradeResult, []UpgradeResult{{PlayerID: player.ID, Choice: selected}})
+	}
+}
+
+// applyUpgrade gives the entity the weapon or passive of the choice at its
+// level or evolves its weapon, and returns whether the choice still holds with
+// the definitions.
+func (w *World) applyUpgrade(id EntityID, choice UpgradeChoice) bool {
+	set := definitions.Current()
+
+	switch choice.Kind {
+	case UpgradeWeapon:
+		definition, ok := set.Weapon(choice.WeaponClassID)
+		if !ok {
+			return false
+		}
+		armament, ok := w.Weapons.Get(id)
+		if !ok {
+			armament = w.Weapons.Add(id, Armament{})
+		}
+
+		upgraded := NewEquippedWeapon(definition, min(choice.Level, definition.MaxLevel()))
+		for i, weapon := range armament.Weapons {
+			if weapon.WeaponClassID == choice.WeaponClassID {
+				upgraded.WeaponID, upgraded.EvolvedFrom, upgraded.Cooldown = weapon.WeaponID, weapon.EvolvedFrom, weapon.Cooldown
+				armament.Weapons[i] = upgraded
+				return true
+			}
+		}
+		armament.Weapons = append(armament.Weapons, upgraded)
+		return true
+
+	case UpgradeEvolution:
+		_, into, ok := set.Evolution(choice.EvolvesFrom)
+		if !ok || into.ID != choice.WeaponClassID {
+			return false
+		}
+		armament, ok := w.Weapons.Get(id)
+		if !ok {
+			return false
+		}
+
+		for i, weapon := range armament.Weapons {
+			if weapon.WeaponClassID == choice.EvolvesFrom {
+				evolved := NewEquippedWeapon(into, 1)
+				evolved.WeaponID, evolved.EvolvedFrom = weapon.WeaponID, weapon.WeaponClassID
+				armament.Weapons[i] = evolved
+				return true
+			}
+		}
+		return false
+
+	case UpgradePassive:
+		definition, ok := set.Passive(choice.Passive)
+		if !ok {
+			return false
+		}
+		passives, ok := w.Passives.Get(id)
+		if !ok {
+			passives = w.Passives.Add(id, Passives{Levels: make(map[string]uint)})
+		}
+
+		passives.Levels[choice.Passive] = min(choice.Level, definition.MaxLevel)
+		w.refreshModifiers(id)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package game
+
+import (
+	"math/rand/v2"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// placementAttempts is how many random positions are tried per area before
+// giving up on placing it.
+const placementAttempts = 20
+
+// NewSeed draws a map seed, kept below 2^53 so clients read it exactly.
+func NewSeed() uint64 {
+	return rand.Uint64N(1<<53-1) + 1
+}
+
+// GenerateLayout places the spawn areas, obstacles and terrain zones of a map
+// drawn from the seed, the same seed and tuning always giving the same map.
+// Obstacles and zones are kept out of the spawn areas and obstacles do not
+// overlap each other.
+func GenerateLayout(seed uint64, tuning definitions.MapTuning) definitions.Layout {
+	random := rand.New(rand.NewPCG(seed, seed))
+	return generateLayout(random, [2]int{}, WorldSize, tuning.SpawnAreas, tuning)
+}
+
+// generateLayout places the areas of a layout inside the square region of the
+// size starting at the origin.
+func generateLayout(random *rand.Rand, origin [2]int, region int, spawnAreas uint, tuning definitions.MapTuning) definitions.Layout {
+	var layout definitions.Layout
+
+	between := func(sizes [2]uint) uint {
+		return sizes[0] + uint(random.IntN(int(sizes[1]-sizes[0])+1))
+	}
+	place := func(size uint, avoid ...[]definitions.Area) (definitions.Area, bool) {
+	attempts:
+		for range placementAttempts {
+			var area definitions.Area
+			area.Size = size
+			for axis := range 2 {
+				area.Position[axis] = origin[axis] + clampToRegion(random.IntN(region), size, region)
+			}
+
+			box := newAABB(area.Position, size)
+			for _, areas := range avoid {
+				for _, other := range areas {
+					if box.overlaps(newAABB(other.Position, other.Size)) {
+						continue attempts
+					}
+				}
+			}
+			return area, true
+		}
+		return definitions.Area{}, false
+	}
+
+	for range spawnAreas {
+		if area, ok := place(tuning.SpawnAreaSize, layout.SpawnAreas); ok {
+			layout.SpawnAreas = append(layout.SpawnAreas, area)
+		}
+	}
+	for range tuning.Obstacles {
+		if area, ok := place(between(tuning.ObstacleSize), layout.SpawnAreas, layout.Obstacles); ok {
+			layout.Obstacles = append(layout.Obstacles, area)
+		}
+	}
+	for range tuning.Zones {
+		area, ok := place(between(tuning.ZoneSize), layout.SpawnAreas)
+		if !ok {
+			continue
+		}
+		zone := definitions.Zone{Area: area, Kind: definitions.ZoneSlow, Amount: tuning.Slow}
+		if random.IntN(2) == 0 {
+			zone.Kind, zone.Amount = definitions.ZoneDamage, tuning.Damage
+		}
+		layout.Zones = append(layout.Zones, zone)
+	}
+
+	return layout
+}
+
+// LoadMap adds the obstacles and terrain zones of the layout to the world,
+// seed being 0 and name the file for layouts loaded from a file. The caller
+// must hold the world lock.
+func (w *World) LoadMap(seed uint64, name string, layout definitions.Layout) {
+	for _, obstacle := range layout.Obstacles {
+		w.SpawnObstacle(obstacle.Position, obstacle.Size)
+	}
+	for _, zone := range layout.Zones {
+		w.spawnZone(zone)
+	}
+
+	w.layout = MapLayout{
+		Seed:           seed,
+		Name:           name,
+		Size:           WorldSize,
+		DamageInterval: definitions.Current().Tuning.Map.DamageInterval,
+		Layout:         layout,
+	}
+}
+
+// spawnZone adds a terrain zone to the world.
+func (w *World) spawnZone(zone definitions.Zone) EntityID {
+	id := w.NewEntity()
+	w.Transforms.Add(id, Transform{Position: zone.Position})
+	w.Terrains.Add(id, Terrain{Kind: zone.Kind, Amount: zone.Amount, Size: zone.Size})
+	return id
+}
+
+// HandleConnect sends the map to a client that just connected.
+func HandleConnect(client *ws.Client) {
+	world.RLock()
+	layout := world.layout
+	chunks := world.loadedChunks()
+	world.RUnlock()
+
+	hub := ws.GetHub()
+	hub.SendDirect(client.SessionID, ws.Message{Type: ServerMapLayout, Data: layout})
+	if len(chunks) != 0 {
+		hub.SendDirect(client.SessionID, ws.Message{Type: ServerMapChunksLoad, Data: chunks})
+	}
+}
+
+// terrainAmount returns the highest amount of the zones of the kind the
+// entity stands in, 0 if none.
+func (w *World) terrainAmount(id EntityID, kind string) uint {
+	if w.Terrains.Len() == 0 || !w.Colliders.Has(id) {
+		return 0
+	}
+
+	box := w.box(id)
+	var amount uint
+	w.Terrains.Each(func(zone EntityID, terrain *Terrain) {
+		transform, _ := w.Transforms.Get(zone)
+		if terrain.Kind == kind && box.overlaps(newAABB(transform.Position, terrain.Size)) {
+			amount = max(amount, terrain.Amount)
+		}
+	})
+	return amount
+}
+
+// updateTerrain hurts the standing players and the enemies in damage zones
+// every damage interval.
+func updateTerrain(w *World, tick uint64) {
+	interval := definitions.Current().Tuning.Map.DamageInterval
+	if w.Terrains.Len() == 0 || interval == 0 || tick%uint64(interval) != 0 {
+		return
+	}
+
+	var damagedPlayers, damagedEnemies []uint
+	w.Healths.Each(func(id EntityID, _ *Health) {
+		amount := w.terrainAmount(id, definitions.ZoneDamage)
+		if amount == 0 {
+			return
+		}
+
+		if player, ok := w.Players.Get(id); ok {
+			if !w.IsDowned(id) && w.damage(id, w.damageTaken(id, amount)) {
+				damagedPlayers = append(damagedPlayers, player.ID)
+			}
+		} else if w.AIs.Has(id) && w.damage(id, amount) {
+			damagedEnemies = append(damagedEnemies, id)
+		}
+	})
+
+	if len(damagedPlayers) != 0 {
+		w.Emit(ServerDamagePlayers, damagedPlayers)
+	}
+	if len(damagedEnemies) != 0 {
+		w.Emit(ServerDamageEnemies, damagedEnemies)
+	}
+}
+
+// obstructed reports whether a square of the size at the position overlaps an
+// obstacle.
+func (w *World) obstructed(position [2]int, size uint) bool {
+	box := newAABB(position, size)
+	blocked := false
+	w.Colliders.Each(func(id EntityID, collider *Collider) {
+		if collider.Static && !blocked && box.overlaps(w.box(id)) {
+			blocked = true
+		}
+	})
+	return blocked
+}
+
+// freePosition returns the position if a square of the size fits there,
+// otherwise a free position in one of the map's spawn areas, or around the
+// position for maps without any, falling back to the position if none is
+// found.
+func (w *World) freePosition(position [2]int, size uint) [2]int {
+	if !w.obstructed(position, size) {
+		return position
+	}
+	if candidate, ok := w.SpawnAreaPosition(size); ok {
+		return candidate
+	}
+
+	for attempt := range placementAttempts {
+		spread := int(size) * (attempt + 2)
+		var candidate [2]int
+		for axis := range 2 {
+			offset := rand.IntN(2*spread+1) - spread
+			candidate[axis] = w.clamp(position[axis]+offset, size)
+		}
+		if !w.obstructed(candidate, size) {
+			return candidate
+		}
+	}
+	return position
+}
+
+// SpawnAreaPosition returns a free position for a square of the size inside
+// one of the map's spawn areas, false if the map has none or none is found.
+// The caller must hold the world lock.
+func (w *World) SpawnAreaPosition(size uint) ([2]int, bool) {
+	areas := w.layout.SpawnAreas
+	if len(areas) == 0 {
+		return [2]int{}, false
+	}
+
+	for range placementAttempts {
+		area := areas[rand.IntN(len(areas))]
+		spread := max(0, int(area.Size/2)-int(size-size/2))
+		var candidate [2]int
+		for axis := range 2 {
+			offset := rand.IntN(2*spread+1) - spread
+			candidate[axis] = w.clamp(area.Position[axis]+offset, size)
+		}
+		if !w.obstructed(candidate, size) {
+			return candidate, true
+		}
+	}
+	return [2]int{}, false
+}
+
+// inSpawnArea reports whether a square of the size at the position overlaps
+// one of the map's spawn areas.
+func (w *World) inSpawnArea(position [2]int, size uint) bool {
+	box := newAABB(position, size)
+	for _, area := range w.layout.SpawnAreas {
+		if box.overlaps(newAABB(area.Position, area.Size)) {
+			return true
+		}
+	}
+	return false
+}
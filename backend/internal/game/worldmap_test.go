@@ -0,0 +1,102 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+)
+
+var testMapTuning = definitions.MapTuning{
+	Obstacles:      30,
+	ObstacleSize:   [2]uint{48, 160},
+	Zones:          6,
+	ZoneSize:       [2]uint{128, 320},
+	Slow:           40,
+	Damage:         5,
+	DamageInterval: 30,
+	SpawnAreas:     4,
+	SpawnAreaSize:  256,
+}
+
+func overlapsAny(area definitions.Area, others []definitions.Area) bool {
+	box := newAABB(area.Position, area.Size)
+	for _, other := range others {
+		if box.overlaps(newAABB(other.Position, other.Size)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGenerateLayoutDeterministic(t *testing.T) {
+	first := GenerateLayout(42, testMapTuning)
+	if second := GenerateLayout(42, testMapTuning); !reflect.DeepEqual(first, second) {
+		t.Error("the seed gave two different layouts")
+	}
+	if reflect.DeepEqual(first, GenerateLayout(43, testMapTuning)) {
+		t.Error("another seed gave the same layout")
+	}
+
+	if len(first.SpawnAreas) != int(testMapTuning.SpawnAreas) {
+		t.Errorf("%d spawn areas, want %d", len(first.SpawnAreas), testMapTuning.SpawnAreas)
+	}
+	if len(first.Obstacles) == 0 || len(first.Zones) == 0 {
+		t.Fatalf("layout has %d obstacles and %d zones", len(first.Obstacles), len(first.Zones))
+	}
+	for _, obstacle := range first.Obstacles {
+		if overlapsAny(obstacle, first.SpawnAreas) {
+			t.Errorf("obstacle %+v is in a spawn area", obstacle)
+		}
+	}
+	for _, zone := range first.Zones {
+		if overlapsAny(zone.Area, first.SpawnAreas) {
+			t.Errorf("zone %+v is in a spawn area", zone)
+		}
+	}
+}
+
+func TestSpawnAreaPosition(t *testing.T) {
+	w := NewWorld()
+	if _, ok := w.SpawnAreaPosition(PlayerSize); ok {
+		t.Fatal("found a spawn area position on a map without spawn areas")
+	}
+
+	area := definitions.Area{Position: [2]int{500, 500}, Size: 200}
+	w.layout.SpawnAreas = []definitions.Area{area}
+	// Block the left half of the area
+	w.SpawnObstacle([2]int{450, 500}, 100)
+
+	box := newAABB(area.Position, area.Size)
+	for range 200 {
+		position, ok := w.SpawnAreaPosition(PlayerSize)
+		if !ok {
+			t.Fatal("found no position in the free half of the area")
+		}
+		player := newAABB(position, PlayerSize)
+		if player.Min[0] < box.Min[0] || player.Min[1] < box.Min[1] || player.Max[0] > box.Max[0] || player.Max[1] > box.Max[1] {
+			t.Fatalf("position %v is out of the spawn area", position)
+		}
+		if w.obstructed(position, PlayerSize) {
+			t.Fatalf("position %v is obstructed", position)
+		}
+	}
+}
+
+func TestSpawnPositionAvoidsSpawnAreas(t *testing.T) {
+	previous := definitions.Current()
+	defer definitions.SetCurrent(previous)
+	definitions.SetCurrent(&definitions.Set{Tuning: definitions.Tuning{
+		Spawner: definitions.SpawnerTuning{MinDistance: 100, MaxDistance: 300},
+	}})
+
+	w := NewWorld()
+	around := [2]int{HalfWorldSize, HalfWorldSize}
+	// The area covers the lower part of the spawn ring around the position
+	w.layout.SpawnAreas = []definitions.Area{{Position: [2]int{HalfWorldSize, HalfWorldSize + 400}, Size: 700}}
+
+	for range 200 {
+		if position := w.spawnPosition(around, 10); w.inSpawnArea(position, 10) {
+			t.Fatalf("enemy spawned in a spawn area at %v", position)
+		}
+	}
+}
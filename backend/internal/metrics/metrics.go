@@ -0,0 +1,253 @@
+// Package metrics keeps counters, gauges and histograms and writes them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds, from 100µs to 10s.
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]metric)
+)
+
+func register(name string, m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry[name] = m
+}
+
+// labelValues identifies a label set by its pairs in the exposition format.
+type labelValues = string
+
+func joinLabels(names []string, values []string) string {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(names), len(values)))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(values[i])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func withLabel(labels string, pair string) string {
+	if len(labels) == 0 {
+		return "{" + pair + "}"
+	}
+	return "{" + labels + "," + pair + "}"
+}
+
+func braces(labels string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[labelValues]V) []labelValues {
+	keys := make([]labelValues, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is a value that only goes up, partitioned by labels.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[labelValues]float64
+}
+
+func NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[labelValues]float64)}
+	register(name, c)
+	return c
+}
+
+func (c *Counter) Add(v float64, labels ...string) {
+	key := joinLabels(c.labels, labels)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, braces(key), formatFloat(c.values[key]))
+	}
+}
+
+// Gauge is a value read when the metrics are scraped, one per label set
+// returned by its function.
+type Gauge struct {
+	name, help string
+	labels     []string
+	read       func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge without labels whose value is read from f.
+func NewGaugeFunc(name string, help string, f func() float64) *Gauge {
+	g := &Gauge{name: name, help: help, read: func() map[string]float64 {
+		return map[string]float64{"": f()}
+	}}
+	register(name, g)
+	return g
+}
+
+// NewGaugeVecFunc registers a gauge with a single label, f returns the value
+// of each label value.
+func NewGaugeVecFunc(name string, help string, label string, f func() map[string]float64) *Gauge {
+	g := &Gauge{name: name, help: help, labels: []string{label}, read: f}
+	register(name, g)
+	return g
+}
+
+func (g *Gauge) write(w io.Writer) {
+	values := g.read()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, value := range sortedKeys(values) {
+		labels := ""
+		if len(g.labels) != 0 {
+			labels = joinLabels(g.labels, []string{value})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.name, braces(labels), formatFloat(values[value]))
+	}
+}
+
+type histogramValues struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Histogram counts observations in buckets, partitioned by labels.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[labelValues]*histogramValues
+}
+
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[labelValues]*histogramValues),
+	}
+	register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	key := joinLabels(h.labels, labels)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	values, ok := h.values[key]
+	if !ok {
+		values = &histogramValues{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = values
+	}
+
+	values.count++
+	values.sum += v
+	for i, bound := range h.buckets {
+		if v <= bound {
+			values.counts[i]++
+			break
+		}
+	}
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		values := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += values.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, `le="`+formatFloat(bound)+`"`), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, `le="+Inf"`), values.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braces(key), formatFloat(values.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, braces(key), values.count)
+	}
+}
+
+// Write writes every registered metric to w, sorted by name.
+func Write(w io.Writer) {
+	registryMu.Lock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	metrics := make([]metric, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		metrics[i] = registry[name]
+	}
+	registryMu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registered metrics to Prometheus.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Write(w)
+}
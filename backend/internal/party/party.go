@@ -0,0 +1,436 @@
+// Package party keeps the short-lived groups players form to play runs
+// together. Parties are independent of clans and only live in memory, a
+// member leaves their party when they disconnect and a party is disbanded
+// once its last member left. The first member of a party leads it.
+package party
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/friends"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// Member is a player in a party.
+type Member struct {
+	PlayerID uint
+	Username string
+}
+
+type party struct {
+	id      uint
+	members []Member // in join order, the first one leads
+	invited []string // usernames
+}
+
+var (
+	mu       sync.Mutex
+	lastID   uint
+	parties  = make(map[uint]*party)
+	memberOf = make(map[string]uint) // username -> party ID
+)
+
+var (
+	errNotLeader     = errors.New("only the party leader can do that")
+	errInParty       = errors.New("user is already in a party")
+	errInvited       = errors.New("user is already invited")
+	errFull          = errors.New("party is full")
+	errNotInvited    = errors.New("no pending invite to that party")
+	errNotInParty    = errors.New("user is not in a party")
+	errNotMember     = errors.New("user is not a member or invited")
+	errInviteSelf    = errors.New("cannot invite yourself")
+	errOffline       = errors.New("user is not online")
+	errNoClanmates   = errors.New("no online clanmate to invite")
+	errInvalidInvite = errors.New("invalid party request")
+)
+
+func sendError(sessionID string, message string) {
+	ws.GetHub().SendDirect(sessionID, ws.Message{Type: game.ServerPartyError, Data: message})
+}
+
+// outbox collects the messages built while holding mu, they are sent once it
+// is released so that a slow hub never stalls every party.
+type outbox []ws.DirectMessage
+
+// send queues a message to every connected client of the user.
+func (o *outbox) send(online map[string][]string, username string, message ws.Message) {
+	for _, sessionID := range online[username] {
+		*o = append(*o, ws.DirectMessage{SessionID: sessionID, Message: message})
+	}
+}
+
+// fail queues an error for the client of the session.
+func (o *outbox) fail(sessionID string, message string) {
+	*o = append(*o, ws.DirectMessage{SessionID: sessionID, Message: ws.Message{Type: game.ServerPartyError, Data: message}})
+}
+
+// flush sends the queued messages, it must be called without holding mu.
+func (o *outbox) flush() {
+	hub := ws.GetHub()
+	for _, direct := range *o {
+		hub.SendDirect(direct.SessionID, direct.Message)
+	}
+	*o = nil
+}
+
+// decode reads the data of the message into value.
+func decode(message ws.Message, value any) bool {
+	data, err := json.Marshal(message.Data)
+	return err == nil && json.Unmarshal(data, value) == nil
+}
+
+// Members returns the members of the user's party, leader first, false if the
+// user is in no party.
+func Members(username string) ([]Member, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := parties[memberOf[username]]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(p.members), true
+}
+
+// state builds the party sent to clients. The caller must hold mu.
+func (p *party) state(online map[string][]string) game.Party {
+	world := game.GetWorld()
+	world.RLock()
+	defer world.RUnlock()
+
+	state := game.Party{ID: p.id, Leader: p.members[0].Username, Invited: slices.Clone(p.invited)}
+	for _, member := range p.members {
+		presence := game.PresenceOffline
+		if _, ok := online[member.Username]; ok {
+			presence = game.PresenceOnline
+			if _, ok := world.PlayerEntity(member.PlayerID); ok {
+				presence = game.PresenceSpawned
+			}
+		}
+		state.Members = append(state.Members, game.PartyMember{PlayerID: member.PlayerID, Username: member.Username, Presence: presence})
+	}
+	return state
+}
+
+// publish queues the party for its members. The caller must hold mu.
+func (p *party) publish(online map[string][]string, out *outbox) {
+	message := ws.Message{Type: game.ServerPartyUpdate, Data: p.state(online)}
+	for _, member := range p.members {
+		out.send(online, member.Username, message)
+	}
+}
+
+// PublishPresence pushes the user's party to its members, to be called when
+// the user spawns, despawns or dies.
+func PublishPresence(username string) {
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if p, ok := parties[memberOf[username]]; ok {
+		p.publish(friends.OnlineSessions(), &out)
+	}
+}
+
+// setParty tells the world which party the player plays in, 0 for none.
+func setParty(playerID uint, partyID uint) {
+	world := game.GetWorld()
+	world.Lock()
+	world.SetParty(playerID, partyID)
+	world.Unlock()
+}
+
+// invite invites the user to the party of the leader, making a party for the
+// leader if they are in none. The caller must hold mu.
+func invite(leader Member, username string) (*party, error) {
+	p := parties[memberOf[leader.Username]]
+	switch {
+	case p != nil && p.members[0].Username != leader.Username:
+		return nil, errNotLeader
+	case username == leader.Username:
+		return nil, errInviteSelf
+	case memberOf[username] != 0:
+		return nil, errInParty
+	case p != nil && slices.Contains(p.invited, username):
+		return nil, errInvited
+	case p != nil && uint(len(p.members)+len(p.invited)) >= definitions.Current().Tuning.Party.MaxSize:
+		return nil, errFull
+	}
+
+	if p == nil {
+		lastID++
+		p = &party{id: lastID, members: []Member{leader}}
+		parties[p.id] = p
+		memberOf[leader.Username] = p.id
+		setParty(leader.PlayerID, p.id)
+	}
+	p.invited = append(p.invited, username)
+	return p, nil
+}
+
+// leave removes the member from their party, the next member leading it if
+// they led it, and disbands it if it is left empty. The caller must hold mu.
+func leave(username string, online map[string][]string, out *outbox) error {
+	p, ok := parties[memberOf[username]]
+	if !ok {
+		return errNotInParty
+	}
+
+	i := slices.IndexFunc(p.members, func(member Member) bool { return member.Username == username })
+	setParty(p.members[i].PlayerID, 0)
+	p.members = slices.Delete(p.members, i, i+1)
+	delete(memberOf, username)
+	out.send(online, username, ws.Message{Type: game.ServerPartyLeave, Data: p.id})
+
+	if len(p.members) == 0 {
+		delete(parties, p.id)
+		return nil
+	}
+	p.publish(online, out)
+	return nil
+}
+
+// loadMember returns the user as a party member.
+func loadMember(username string) (Member, error) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	player, err := db.GetPlayerByUsername(c, username)
+	if err != nil {
+		return Member{}, err
+	}
+	return Member{PlayerID: player.ID, Username: username}, nil
+}
+
+// inviteAll invites the users to the party of the leader, pushing the invites
+// and the party. It returns how many were invited and the error of the last
+// user who could not be.
+func inviteAll(client *ws.Client, usernames []string) (int, error) {
+	leader, err := loadMember(client.Username)
+	if err != nil {
+		log.Println("Error loading party leader:", err)
+		return 0, errors.New("failed to invite")
+	}
+
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	online := friends.OnlineSessions()
+	invited := 0
+	var p *party
+	for _, username := range usernames {
+		if _, ok := online[username]; !ok {
+			err = errOffline
+			continue
+		}
+		found, inviteErr := invite(leader, username)
+		if inviteErr != nil {
+			err = inviteErr
+			continue
+		}
+		p = found
+		invited++
+		out.send(online, username, ws.Message{Type: game.ServerPartyInvite, Data: game.PartyInvite{PartyID: p.id, Leader: leader.Username}})
+	}
+	if p != nil {
+		p.publish(online, &out)
+	}
+	return invited, err
+}
+
+// HandleClientInvite invites the user named in the message to the sender's
+// party.
+func HandleClientInvite(client *ws.Client, message ws.Message) {
+	var username string
+	if !decode(message, &username) || len(username) == 0 {
+		sendError(client.SessionID, errInvalidInvite.Error())
+		return
+	}
+
+	if _, err := inviteAll(client, []string{username}); err != nil {
+		sendError(client.SessionID, err.Error())
+	}
+}
+
+// HandleClientInviteClan invites every online clanmate of the sender who is
+// not in a party yet to the sender's party, as many as it has room for.
+func HandleClientInviteClan(client *ws.Client, message ws.Message) {
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	player, err := db.GetPlayerByUsername(c, client.Username)
+	if err != nil {
+		log.Println("Error loading party leader:", err)
+		sendError(client.SessionID, "failed to invite")
+		return
+	}
+	if player.ClanID == nil {
+		sendError(client.SessionID, "user is not in a clan")
+		return
+	}
+
+	names, err := db.GetClanMemberNames(c, *player.ClanID)
+	if err != nil {
+		log.Println("Error loading clan members:", err)
+		sendError(client.SessionID, "failed to invite")
+		return
+	}
+
+	mu.Lock()
+	online := friends.OnlineSessions()
+	clanmates := slices.DeleteFunc(names, func(name string) bool {
+		_, ok := online[name]
+		return !ok || name == client.Username || memberOf[name] != 0
+	})
+	mu.Unlock()
+
+	if len(clanmates) == 0 {
+		sendError(client.SessionID, errNoClanmates.Error())
+		return
+	}
+	if invited, err := inviteAll(client, clanmates); invited == 0 {
+		sendError(client.SessionID, err.Error())
+	}
+}
+
+// HandleClientAccept makes the sender join the party of the invite.
+func HandleClientAccept(client *ws.Client, message ws.Message) {
+	var partyID uint
+	if !decode(message, &partyID) {
+		sendError(client.SessionID, errInvalidInvite.Error())
+		return
+	}
+
+	member, err := loadMember(client.Username)
+	if err != nil {
+		log.Println("Error loading party member:", err)
+		sendError(client.SessionID, "failed to join the party")
+		return
+	}
+
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := parties[partyID]
+	switch {
+	case !ok || !slices.Contains(p.invited, client.Username):
+		out.fail(client.SessionID, errNotInvited.Error())
+		return
+	case memberOf[client.Username] != 0:
+		out.fail(client.SessionID, errInParty.Error())
+		return
+	case uint(len(p.members)) >= definitions.Current().Tuning.Party.MaxSize:
+		out.fail(client.SessionID, errFull.Error())
+		return
+	}
+
+	p.invited = slices.DeleteFunc(p.invited, func(name string) bool { return name == client.Username })
+	p.members = append(p.members, member)
+	memberOf[client.Username] = p.id
+	setParty(member.PlayerID, p.id)
+	p.publish(friends.OnlineSessions(), &out)
+}
+
+// HandleClientDecline turns down an invite to a party.
+func HandleClientDecline(client *ws.Client, message ws.Message) {
+	var partyID uint
+	if !decode(message, &partyID) {
+		sendError(client.SessionID, errInvalidInvite.Error())
+		return
+	}
+
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := parties[partyID]
+	if !ok || !slices.Contains(p.invited, client.Username) {
+		out.fail(client.SessionID, errNotInvited.Error())
+		return
+	}
+	p.invited = slices.DeleteFunc(p.invited, func(name string) bool { return name == client.Username })
+	p.publish(friends.OnlineSessions(), &out)
+}
+
+// HandleClientLeave makes the sender leave their party.
+func HandleClientLeave(client *ws.Client, message ws.Message) {
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := leave(client.Username, friends.OnlineSessions(), &out); err != nil {
+		out.fail(client.SessionID, err.Error())
+	}
+}
+
+// HandleClientKick removes the member named in the message from the sender's
+// party, or cancels their invite.
+func HandleClientKick(client *ws.Client, message ws.Message) {
+	var username string
+	if !decode(message, &username) || len(username) == 0 {
+		sendError(client.SessionID, errInvalidInvite.Error())
+		return
+	}
+
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := parties[memberOf[client.Username]]
+	switch {
+	case !ok:
+		out.fail(client.SessionID, errNotInParty.Error())
+		return
+	case p.members[0].Username != client.Username:
+		out.fail(client.SessionID, errNotLeader.Error())
+		return
+	}
+
+	online := friends.OnlineSessions()
+	if i := slices.Index(p.invited, username); i >= 0 {
+		p.invited = slices.Delete(p.invited, i, i+1)
+		p.publish(online, &out)
+	} else if username != client.Username && memberOf[username] == p.id {
+		leave(username, online, &out)
+	} else {
+		out.fail(client.SessionID, errNotMember.Error())
+	}
+}
+
+// HandleDisconnect removes a user who went away from their party and its
+// invites, unless they are still connected from elsewhere.
+func HandleDisconnect(client *ws.Client) {
+	var out outbox
+	defer out.flush()
+	mu.Lock()
+	defer mu.Unlock()
+
+	online := friends.OnlineSessions()
+	if _, ok := online[client.Username]; ok {
+		return
+	}
+
+	for _, p := range parties {
+		if i := slices.Index(p.invited, client.Username); i >= 0 {
+			p.invited = slices.Delete(p.invited, i, i+1)
+			p.publish(online, &out)
+		}
+	}
+	leave(client.Username, online, &out)
+}
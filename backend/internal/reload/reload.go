@@ -0,0 +1,107 @@
+// Package reload swaps the gameplay definitions of the running server for the
+// ones on disk, when an admin asks for it or when the files change.
+package reload
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/errs"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
+)
+
+// DataDir is the directory the definitions are reloaded from.
+var DataDir = "data"
+
+var (
+	ErrInvalid  = errs.Validation("invalid definitions")
+	ErrRejected = errs.Conflict("definitions do not fit the running world")
+)
+
+var (
+	mu      sync.Mutex
+	reloads = metrics.NewCounter("definitions_reloads_total", "Definition reloads, by result.", "result")
+)
+
+// Reload loads the definitions of DataDir and applies them to the world at the
+// next tick boundary. Invalid definitions, or ones the world cannot switch to,
+// are rejected and the current ones stay in use.
+func Reload(ctx context.Context) (*definitions.Set, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	set, err := definitions.Load(DataDir)
+	if err != nil {
+		reloads.Inc("invalid")
+		return nil, failed(ErrInvalid, err)
+	}
+
+	if err := game.ApplyDefinitions(ctx, set); err != nil {
+		reloads.Inc("rejected")
+		return nil, failed(ErrRejected, err)
+	}
+
+	if err := db.SyncWeaponClasses(ctx, set.Weapons); err != nil {
+		reloads.Inc("applied")
+		return set, fmt.Errorf("definitions applied but weapon classes not synced: %w", err)
+	}
+
+	reloads.Inc("applied")
+	log.Printf("Reloaded definitions from %s: %d weapons, %d projectiles, %d enemies, %d ticks per second",
+		DataDir, len(set.Weapons), len(set.Projectiles), len(set.Enemies), set.Tuning.TickRate)
+	return set, nil
+}
+
+// failed returns e carrying err as its cause and its lines as details.
+func failed(e *errs.Error, err error) error {
+	failure := e.WithDetails(map[string]any{"problems": strings.Split(err.Error(), "\n")})
+	failure.Err = err
+	return failure
+}
+
+// Watch polls the definition files every interval and reloads them when one of
+// them changes, until ctx is done.
+func Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := modTimes()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := modTimes()
+		if slices.Equal(current, last) {
+			continue
+		}
+		last = current
+
+		if _, err := Reload(ctx); err != nil {
+			log.Println("Failed to reload changed definitions, keeping the current ones:", err)
+		}
+	}
+}
+
+// modTimes returns the modification times of the definition files, zero for
+// missing ones.
+func modTimes() []int64 {
+	files := definitions.Files(DataDir)
+	times := make([]int64, len(files))
+	for i, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			times[i] = info.ModTime().UnixNano()
+		}
+	}
+	return times
+}
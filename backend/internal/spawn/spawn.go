@@ -0,0 +1,255 @@
+// Package spawn moves players between the database and the world when their
+// clients ask to spawn or despawn, disconnect or die.
+package spawn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/friends"
+	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/party"
+	"valley-of-survival-dawn-of-squares/internal/session"
+	"valley-of-survival-dawn-of-squares/internal/ws"
+)
+
+// loadWeapons returns the player's weapons with the stats of their definitions.
+func loadWeapons(c context.Context, playerID uint) ([]game.EquippedWeapon, error) {
+	weapons, err := db.GetPlayerWeapons(c, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := definitions.Current()
+	equipped := make([]game.EquippedWeapon, 0, len(weapons))
+	for _, weapon := range weapons {
+		definition, ok := set.Weapon(weapon.WeaponClassID)
+		if !ok {
+			log.Printf("Player %d has weapon %d of undefined class %d, skipping it", playerID, weapon.ID, weapon.WeaponClassID)
+			continue
+		}
+		equippedWeapon := game.NewEquippedWeapon(definition, min(weapon.Level, definition.MaxLevel()))
+		equippedWeapon.WeaponID = weapon.ID
+		equipped = append(equipped, equippedWeapon)
+	}
+	return equipped, nil
+}
+
+// loadSnapshot returns the state the player is spawned with: their weapons,
+// passives and pending level-up offer.
+func loadSnapshot(c context.Context, player game.Player) (game.PlayerSnapshot, error) {
+	weapons, err := loadWeapons(c, player.ID)
+	if err != nil {
+		return game.PlayerSnapshot{}, err
+	}
+	passives, err := db.GetPlayerPassives(c, player.ID)
+	if err != nil {
+		return game.PlayerSnapshot{}, err
+	}
+	level, offer, err := db.GetPlayerUpgrade(c, player.ID)
+	if err != nil {
+		return game.PlayerSnapshot{}, err
+	}
+
+	return game.PlayerSnapshot{
+		Player:       player,
+		Weapons:      weapons,
+		Passives:     passives,
+		UpgradeLevel: level,
+		Offer:        offer,
+	}, nil
+}
+
+func sendError(sessionID string, message string) {
+	ws.GetHub().SendDirect(sessionID, ws.Message{Type: game.ServerPlayerSpawnError, Data: message})
+}
+
+// partyOffsets place party members around their leader, in player sizes.
+var partyOffsets = [][2]int{{2, 0}, {-2, 0}, {0, 2}, {0, -2}, {2, 2}, {-2, -2}, {2, -2}, {-2, 2}}
+
+// readMode returns the run mode sent with a spawn request, the default
+// difficulty if none was picked, with duplicate curses dropped.
+func readMode(message ws.Message) (game.RunMode, error) {
+	var mode game.RunMode
+	if message.Data != nil {
+		data, err := json.Marshal(message.Data)
+		if err != nil || json.Unmarshal(data, &mode) != nil {
+			return game.RunMode{}, errors.New("invalid run mode")
+		}
+	}
+
+	set := definitions.Current()
+	if mode.Difficulty == "" {
+		mode.Difficulty = set.DefaultDifficulty
+	}
+	if _, ok := set.Difficulty(mode.Difficulty); !ok {
+		return game.RunMode{}, fmt.Errorf("unknown difficulty %q", mode.Difficulty)
+	}
+
+	curses := make([]string, 0, len(mode.Curses))
+	for _, key := range mode.Curses {
+		if _, ok := set.Curse(key); !ok {
+			return game.RunMode{}, fmt.Errorf("unknown curse %q", key)
+		}
+		if !slices.Contains(curses, key) {
+			curses = append(curses, key)
+		}
+	}
+	mode.Curses = curses
+	return mode, nil
+}
+
+// spawnPlayer spawns the user's player for the session in a run of the mode,
+// next to the position of the slot around the leader if near is set.
+func spawnPlayer(sessionID string, username string, mode game.RunMode, near *[2]int, slot int) {
+	c := context.Background()
+
+	player, err := db.GetPlayerByUsername(c, username)
+	if err != nil {
+		log.Println("Error loading player to spawn:", err)
+		return
+	}
+
+	snapshot, err := loadSnapshot(c, *player)
+	if err != nil {
+		log.Println("Error loading player state to spawn:", err)
+		return
+	}
+	if near != nil {
+		offset := partyOffsets[slot%len(partyOffsets)]
+		for axis := range 2 {
+			snapshot.Player.Position[axis] = near[axis] + offset[axis]*game.PlayerSize
+		}
+	}
+
+	world := game.GetWorld()
+	world.Lock()
+	if wait := world.RespawnCooldown(player.ID); wait > 0 {
+		world.Unlock()
+		sendError(sessionID, fmt.Sprintf("you can spawn again in %d seconds", int(math.Ceil(wait.Seconds()))))
+		return
+	}
+	if near == nil && snapshot.Player.HP == 0 {
+		// New runs start in one of the map's spawn areas
+		if position, ok := world.SpawnAreaPosition(game.PlayerSize); ok {
+			snapshot.Player.Position = position
+		}
+	}
+	world.SpawnPlayer(sessionID, snapshot, mode)
+	world.Unlock()
+
+	if err := db.SpawnPlayer(c, player.ID); err != nil {
+		log.Println("Error marking player as spawned:", err)
+	}
+	friends.PublishPresence(username)
+	party.PublishPresence(username)
+}
+
+// HandleClientSpawn spawns the player of the client. A party leader starts the
+// run of their party, spawning the online members who are not in the world
+// yet around them, while the other members may only join the run of their
+// leader. The run mode the leader picks applies to the whole party.
+func HandleClientSpawn(client *ws.Client, message ws.Message) {
+	mode, err := readMode(message)
+	if err != nil {
+		sendError(client.SessionID, err.Error())
+		return
+	}
+
+	members, ok := party.Members(client.Username)
+	if !ok {
+		spawnPlayer(client.SessionID, client.Username, mode, nil, 0)
+		return
+	}
+
+	leader := members[0]
+	if leader.Username == client.Username {
+		spawnPlayer(client.SessionID, client.Username, mode, nil, 0)
+	}
+
+	world := game.GetWorld()
+	world.RLock()
+	id, spawned := world.PlayerEntity(leader.PlayerID)
+	position := world.PlayerState(id).Position
+	if leaderMode, ok := world.RunMode(id); ok {
+		mode = leaderMode
+	}
+	world.RUnlock()
+
+	if !spawned {
+		if leader.Username != client.Username {
+			sendError(client.SessionID, "the party leader has not started the run")
+		}
+		return
+	}
+	if leader.Username != client.Username {
+		slot := slices.IndexFunc(members, func(member party.Member) bool { return member.Username == client.Username })
+		spawnPlayer(client.SessionID, client.Username, mode, &position, slot-1)
+		return
+	}
+
+	online := friends.OnlineSessions()
+	for slot, member := range members[1:] {
+		sessions := online[member.Username]
+		world.RLock()
+		_, memberSpawned := world.PlayerEntity(member.PlayerID)
+		world.RUnlock()
+		if len(sessions) != 0 && !memberSpawned {
+			spawnPlayer(sessions[0], member.Username, mode, &position, slot)
+		}
+	}
+}
+
+func HandleClientDespawn(client *ws.Client, message ws.Message) {
+	despawn(client)
+}
+
+// HandleDisconnect despawns the player of a client that went away.
+func HandleDisconnect(client *ws.Client) {
+	despawn(client)
+}
+
+// despawn removes the client's player from the world and saves their state.
+func despawn(client *ws.Client) {
+	world := game.GetWorld()
+	world.Lock()
+	var snapshot game.PlayerSnapshot
+	id, ok := world.SessionEntity(client.SessionID)
+	if ok {
+		snapshot, ok = world.DespawnPlayer(world.PlayerState(id).ID)
+	}
+	world.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if err := db.SavePlayerStates(context.Background(), []game.PlayerSnapshot{snapshot}); err != nil {
+		log.Println("Error saving despawned player:", err)
+	}
+	friends.PublishPresence(client.Username)
+	party.PublishPresence(client.Username)
+}
+
+// HandlePlayerDeath saves the state of a player whose run ended in death and
+// records the run.
+func HandlePlayerDeath(death game.PlayerDeath) {
+	c := context.Background()
+
+	if err := db.SavePlayerStates(c, []game.PlayerSnapshot{death.Snapshot}); err != nil {
+		log.Println("Error saving dead player:", err)
+	}
+	if err := db.SaveRun(c, death.Summary); err != nil {
+		log.Println("Error saving run:", err)
+	}
+	if username, ok := session.GetUsername(death.SessionID); ok {
+		friends.PublishPresence(username)
+		party.PublishPresence(username)
+	}
+}
@@ -1,17 +1,76 @@
 package ws
 
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+	"valley-of-survival-dawn-of-squares/internal/metrics"
+)
+
 var hub *Hub = &Hub{
 	Clients:    make(map[string]*Client),
-	Broadcast:  make(chan Message),
+	Broadcast:  make(chan Message, 64),
+	Input:      make(chan Message, 256),
+	Direct:     make(chan DirectMessage, 256),
 	Register:   make(chan *Client),
 	Unregister: make(chan *Client),
+	shutdown:   make(chan Message),
+	done:       make(chan struct{}),
 }
 
+var (
+	_ = metrics.NewGaugeFunc("ws_clients", "Number of connected websocket clients.", func() float64 {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return float64(len(hub.Clients))
+	})
+	_ = metrics.NewGaugeVecFunc("ws_hub_queue_depth", "Number of messages waiting in the hub's queues.", "queue", func() map[string]float64 {
+		hub.mu.RLock()
+		var send int
+		for _, client := range hub.Clients {
+			send += len(client.Send)
+		}
+		hub.mu.RUnlock()
+
+		return map[string]float64{
+			"broadcast":  float64(len(hub.Broadcast)),
+			"input":      float64(len(hub.Input)),
+			"direct":     float64(len(hub.Direct)),
+			"register":   float64(len(hub.Register)),
+			"unregister": float64(len(hub.Unregister)),
+			"send":       float64(send),
+		}
+	})
+)
+
+// HandlerFunc handles a client message of a registered type instead of it
+// being forwarded to the Input channel.
+type HandlerFunc func(client *Client, message Message)
+
+var handlersMu sync.RWMutex
+var handlers = make(map[string]HandlerFunc)
+var connectHandlers []func(client *Client)
+var disconnectHandlers []func(client *Client)
+
 type Hub struct {
+	mu         sync.RWMutex
 	Clients    map[string]*Client
-	Broadcast  chan Message
+	Broadcast  chan Message // sent to every client
+	Input      chan Message // messages of clients without a registered handler, read by the world
+	Direct     chan DirectMessage
 	Register   chan *Client
 	Unregister chan *Client
+
+	shutdown chan Message
+	done     chan struct{} // closed once Run has returned
+	writers  sync.WaitGroup
+}
+
+// DirectMessage is a message sent to a single client only.
+type DirectMessage struct {
+	SessionID string
+	Message   Message
 }
 
 func GetHub() *Hub {
@@ -19,29 +78,200 @@ func GetHub() *Hub {
 }
 
 func GetClient(sessionID string) (*Client, bool) {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
 	client, ok := hub.Clients[sessionID]
 	return client, ok
 }
 
+// RegisterHandler routes client messages of the given type to handler.
+func RegisterHandler(messageType string, handler HandlerFunc) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[messageType] = handler
+}
+
+// OnConnect registers a handler run whenever a client connects.
+func OnConnect(handler func(client *Client)) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	connectHandlers = append(connectHandlers, handler)
+}
+
+// OnDisconnect registers a handler run whenever a client is removed from the hub.
+func OnDisconnect(handler func(client *Client)) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	disconnectHandlers = append(disconnectHandlers, handler)
+}
+
+// notify runs the handlers in their own goroutines, they may send to the hub
+// which would block Run otherwise.
+func notify(handlers *[]func(client *Client), client *Client) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, handler := range *handlers {
+		go handler(client)
+	}
+}
+
+// notifyReplaced runs the disconnect handlers of a client replaced by another
+// one of the same session then the connect handlers of its replacement, in
+// that order, so that the replacement does not look disconnected afterwards.
+// When the same user reconnects their session nothing disconnected and only
+// the connect handlers run.
+func notifyReplaced(replaced *Client, client *Client) {
+	if replaced.Username == client.Username {
+		notify(&connectHandlers, client)
+		return
+	}
+
+	handlersMu.RLock()
+	disconnect, connect := slices.Clone(disconnectHandlers), slices.Clone(connectHandlers)
+	handlersMu.RUnlock()
+
+	go func() {
+		for _, handler := range disconnect {
+			handler(replaced)
+		}
+		for _, handler := range connect {
+			handler(client)
+		}
+	}()
+}
+
+func getHandler(messageType string) (HandlerFunc, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[messageType]
+	return handler, ok
+}
+
+// SessionIDs returns the sessions of all connected clients.
+func (h *Hub) SessionIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sessionIDs := make([]string, 0, len(h.Clients))
+	for sessionID := range h.Clients {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	return sessionIDs
+}
+
+// detachClient removes the client from the hub without running the disconnect
+// handlers, false if it was not connected. It must be called with h.mu held.
+func (h *Hub) detachClient(client *Client) bool {
+	if existing, ok := h.Clients[client.SessionID]; !ok || existing != client {
+		return false
+	}
+	delete(h.Clients, client.SessionID)
+	close(client.Send)
+	return true
+}
+
+// removeClient must be called with h.mu held.
+func (h *Hub) removeClient(client *Client) {
+	if h.detachClient(client) {
+		notify(&disconnectHandlers, client)
+	}
+}
+
+// Shutdown sends message to every client, closes their connections and stops
+// Run. It waits until the clients' pending messages are written or ctx is done.
+func (h *Hub) Shutdown(ctx context.Context, message Message) error {
+	select {
+	case h.shutdown <- message:
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-h.done
+
+	drained := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done is closed once the hub has shut down, sends to its channels would block
+// forever after that.
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// SendDirect queues a message for the client of the session. The message is
+// dropped once the hub has shut down instead of blocking forever.
+func (h *Hub) SendDirect(sessionID string, message Message) {
+	select {
+	case h.Direct <- DirectMessage{SessionID: sessionID, Message: message}:
+	case <-h.done:
+	}
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
+		case message := <-h.shutdown:
+			h.mu.Lock()
+			for sessionID, client := range h.Clients {
+				select {
+				case client.Send <- message:
+				case <-time.After(writeWait):
+				}
+				// Disconnect handlers are skipped, everyone is going offline
+				delete(h.Clients, sessionID)
+				close(client.Send)
+			}
+			h.mu.Unlock()
+			close(h.done)
+			return
 		case client := <-h.Register:
+			h.mu.Lock()
+			existing, replaced := h.Clients[client.SessionID]
+			if replaced {
+				h.detachClient(existing)
+			}
 			h.Clients[client.SessionID] = client
-		case client := <-h.Unregister:
-			if _, ok := h.Clients[client.SessionID]; ok {
-				delete(h.Clients, client.SessionID)
-				close(client.Send)
+			h.mu.Unlock()
+			if replaced {
+				notifyReplaced(existing, client)
+			} else {
+				notify(&connectHandlers, client)
 			}
+		case client := <-h.Unregister:
+			h.mu.Lock()
+			h.removeClient(client)
+			h.mu.Unlock()
 		case message := <-h.Broadcast:
-			for sessionID, client := range h.Clients {
+			h.mu.Lock()
+			for _, client := range h.Clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
-					delete(h.Clients, sessionID)
+					h.removeClient(client)
+				}
+			}
+			h.mu.Unlock()
+		case direct := <-h.Direct:
+			h.mu.Lock()
+			if client, ok := h.Clients[direct.SessionID]; ok {
+				select {
+				case client.Send <- direct.Message:
+				default:
+					h.removeClient(client)
 				}
 			}
+			h.mu.Unlock()
 		}
 	}
 }
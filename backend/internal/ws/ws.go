@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"time"
+	"valley-of-survival-dawn-of-squares/internal/session"
 
 	"github.com/gorilla/websocket"
 )
@@ -22,6 +23,7 @@ type Client struct {
 	Hub       *Hub
 	Conn      *websocket.Conn
 	SessionID string
+	Username  string
 	Send      chan Message
 	Read      chan Message
 }
@@ -32,13 +34,14 @@ type Message struct {
 	Data      any    `json:"data"`
 }
 
-func newClient(conn *websocket.Conn, sessionID string) *Client {
+func newClient(conn *websocket.Conn, sessionID string, username string) *Client {
 	return &Client{
 		Hub:       hub,
 		Conn:      conn,
 		SessionID: sessionID,
-		Send:      make(chan Message),
-		Read:      hub.Broadcast,
+		Username:  username,
+		Send:      make(chan Message, 64),
+		Read:      hub.Input,
 	}
 }
 
@@ -49,14 +52,27 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	username, ok := session.GetUsername(sessionID)
+	if !ok {
+		http.Error(w, "invalid session token", http.StatusUnauthorized)
+		return
+	}
+
 	wsconn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Upgrade failed:", err)
 		http.Error(w, "Upgrade failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	client := newClient(wsconn, sessionID)
-	client.Hub.Register <- client
+	client := newClient(wsconn, sessionID, username)
+	client.Hub.writers.Add(1)
+	select {
+	case client.Hub.Register <- client:
+	case <-client.Hub.done:
+		client.Hub.writers.Done()
+		wsconn.Close()
+		return
+	}
 
 	go client.writePump()
 	go client.readPump()
@@ -64,7 +80,10 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 func (c *Client) readPump() {
 	defer func() {
-		c.Hub.Unregister <- c
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.done:
+		}
 		c.Conn.Close()
 	}()
 
@@ -82,7 +101,19 @@ func (c *Client) readPump() {
 			}
 			break
 		}
-		c.Hub.Broadcast <- msg
+
+		// Clients can only speak for their own session
+		msg.SessionID = c.SessionID
+
+		if handler, ok := getHandler(msg.Type); ok {
+			handler(c, msg)
+			continue
+		}
+		select {
+		case c.Read <- msg:
+		case <-c.Hub.done:
+			return
+		}
 	}
 }
 
@@ -91,6 +122,7 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		c.Hub.writers.Done()
 	}()
 
 	for {
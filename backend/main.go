@@ -1,47 +1,190 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"valley-of-survival-dawn-of-squares/internal/api"
+	"valley-of-survival-dawn-of-squares/internal/chat"
 	"valley-of-survival-dawn-of-squares/internal/db"
+	"valley-of-survival-dawn-of-squares/internal/definitions"
+	"valley-of-survival-dawn-of-squares/internal/friends"
 	"valley-of-survival-dawn-of-squares/internal/game"
+	"valley-of-survival-dawn-of-squares/internal/party"
+	"valley-of-survival-dawn-of-squares/internal/reload"
+	"valley-of-survival-dawn-of-squares/internal/spawn"
 	"valley-of-survival-dawn-of-squares/internal/ws"
 )
 
+var shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "time allowed for a graceful shutdown")
+var dataDir = flag.String("data", "data", "directory of the weapon, enemy and tuning definition files")
+var watchInterval = flag.Duration("watch-interval", 2*time.Second, "how often the definition files are checked for changes, 0 disables reloading on change")
+var adminToken = flag.String("admin-token", "", "bearer token of the admin api, which is disabled when empty")
+
 func main() {
+	flag.Parse()
+
+	set, err := definitions.Load(*dataDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	definitions.SetCurrent(set)
+	reload.DataDir = *dataDir
+	api.AdminToken = *adminToken
+
 	db.InitDB()
 	defer db.CloseDB()
 
-	http.HandleFunc("/", api.HandleFrontend)
+	if err := db.SyncWeaponClasses(context.Background(), set.Weapons); err != nil {
+		log.Fatalln("Failed to sync weapon classes:", err)
+	}
+
+	if err := loadMap(set); err != nil {
+		log.Fatalln("Failed to load the map:", err)
+	}
+
+	ws.RegisterHandler(game.ClientChatSend, chat.HandleClientChat)
+	ws.OnConnect(game.HandleConnect)
+	ws.OnConnect(friends.HandleConnect)
+	ws.RegisterHandler(game.ClientPlayerSpawn, spawn.HandleClientSpawn)
+	ws.RegisterHandler(game.ClientPlayerDespawn, spawn.HandleClientDespawn)
+	ws.OnDisconnect(spawn.HandleDisconnect)
+	game.OnPlayerDeath(spawn.HandlePlayerDeath)
+	ws.OnDisconnect(friends.HandleDisconnect)
+	ws.RegisterHandler(game.ClientPartyInvite, party.HandleClientInvite)
+	ws.RegisterHandler(game.ClientPartyInviteClan, party.HandleClientInviteClan)
+	ws.RegisterHandler(game.ClientPartyAccept, party.HandleClientAccept)
+	ws.RegisterHandler(game.ClientPartyDecline, party.HandleClientDecline)
+	ws.RegisterHandler(game.ClientPartyLeave, party.HandleClientLeave)
+	ws.RegisterHandler(game.ClientPartyKick, party.HandleClientKick)
+	ws.OnDisconnect(party.HandleDisconnect)
+
+	hub := ws.GetHub()
+	go hub.Run()
+
+	worldCtx, stopWorld := context.WithCancel(context.Background())
+	worldDone := make(chan struct{})
+	go func() {
+		game.StartWorld(worldCtx)
+		close(worldDone)
+	}()
+
+	go reloadOnHangup(worldCtx)
+	if *watchInterval > 0 {
+		go reload.Watch(worldCtx, *watchInterval)
+	}
+
+	server := &http.Server{Addr: "0.0.0.0:8080", Handler: api.NewRouter()}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Server listening on 0.0.0.0:8080")
+		serverErr <- server.ListenAndServe()
+	}()
 
-	http.Handle("/api/verify_session", api.HandlerWithAuth(api.HandleVerifySession))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	http.HandleFunc("/api/signup", api.HandleSignup)
-	http.HandleFunc("/api/login", api.HandleLogin)
-	http.HandleFunc("/api/logout", api.HandleLogout)
-	
-	http.HandleFunc("/api/info/user", api.HandleGetUserInfo)
-	http.HandleFunc("/api/info/player", api.HandleGetPlayerInfo)
-	http.HandleFunc("/api/info/clan", api.HandleGetClanInfo)
-	http.HandleFunc("/api/info/weapon_class", api.HandleGetWeaponClassInfo)
-	http.HandleFunc("/api/info/weapon", api.HandleGetWeaponInfo)
+	select {
+	case err := <-serverErr:
+		log.Println("Server stopped:", err)
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	}
+
+	shutdown(server, hub, stopWorld, worldDone)
+}
+
+// loadMap adds the map of the tuning to the world, loading its layout file or
+// generating it from its seed, a random one if unset. Infinite maps are only
+// seeded here, their chunks being generated around the players.
+func loadMap(set *definitions.Set) error {
+	tuning := set.Tuning.Map
+	if tuning.Infinite {
+		seed := cmp.Or(tuning.Seed, game.NewSeed())
+		world := game.GetWorld()
+		world.Lock()
+		defer world.Unlock()
+		world.StartInfiniteMap(seed, tuning)
+		log.Printf("Started infinite map from seed %d", seed)
+		return nil
+	}
+
+	var seed uint64
+	var layout definitions.Layout
+	if tuning.Layout != "" {
+		var err error
+		if layout, err = definitions.LoadLayout(*dataDir, tuning.Layout, game.WorldSize); err != nil {
+			return err
+		}
+		log.Printf("Loaded map layout %s", tuning.Layout)
+	} else {
+		seed = tuning.Seed
+		if seed == 0 {
+			seed = game.NewSeed()
+		}
+		layout = game.GenerateLayout(seed, tuning)
+		log.Printf("Generated map from seed %d", seed)
+	}
+
+	world := game.GetWorld()
+	world.Lock()
+	defer world.Unlock()
+	world.LoadMap(seed, tuning.Layout, layout)
+	return nil
+}
+
+// reloadOnHangup reloads the definitions whenever the process receives SIGHUP.
+func reloadOnHangup(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if _, err := reload.Reload(ctx); err != nil {
+				log.Println("Failed to reload definitions, keeping the current ones:", err)
+			}
+		}
+	}
+}
 
-	http.HandleFunc("/api/info/current_user", api.HandlerWithAuth(api.HandleGetCurrentUserInfo))
-	http.HandleFunc("/api/info/current_player", api.HandlerWithAuth(api.HandleGetCurrentPlayerInfo))
-	http.HandleFunc("/api/info/current_clan", api.HandlerWithAuth(api.HandleGetCurrentClanInfo))
-	http.HandleFunc("/api/info/current_weapons", api.HandlerWithAuth(api.HandleGetCurrentWeaponsInfo))
+// shutdown stops accepting requests, stops the world between two ticks,
+// disconnects the websocket clients and persists the spawned players, all
+// within shutdownTimeout. The world stops first so its ticks don't publish
+// events to a hub that no longer drains them.
+func shutdown(server *http.Server, hub *ws.Hub, stopWorld context.CancelFunc, worldDone <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
 
-	http.HandleFunc("/api/clan/create", api.HandlerWithAuth(api.HandleCreateClan))
-	http.HandleFunc("/api/clan/delete", api.HandlerWithAuth(api.HandleDeleteClan))
-	http.HandleFunc("/api/clan/join", api.HandlerWithAuth(api.HandleJoinClan))
-	http.HandleFunc("/api/clan/leave", api.HandlerWithAuth(api.HandleLeaveClan))
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("Failed to shut down http server:", err)
+	}
 
-	http.HandleFunc("/ws", ws.HandleWebSocket)
+	stopWorld()
+	select {
+	case <-worldDone:
+	case <-ctx.Done():
+		log.Println("World did not stop in time, saving its last state anyway")
+	}
 
-	go ws.GetHub().Run()
-	go game.StartWorld()
+	if err := hub.Shutdown(ctx, ws.Message{Type: game.ServerShutdown, Data: "server is shutting down"}); err != nil {
+		log.Println("Failed to drain websocket clients:", err)
+	}
 
-	log.Println("Server listening on 0.0.0.0:8080")
-	log.Fatalln(http.ListenAndServe("0.0.0.0:8080", nil))
+	players := game.GetWorld().SpawnedPlayersSnapshot()
+	if err := db.SavePlayerStates(ctx, players); err != nil {
+		log.Println("Failed to save player states:", err)
+	} else {
+		log.Printf("Saved the state of %d spawned players", len(players))
+	}
 }
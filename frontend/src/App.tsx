@@ -22,7 +22,7 @@ function App() {
         const session = getSessionID();
         if (session) {
             try {
-                const res = await apiFetch("/api/verify_session");
+                const res = await apiFetch("/api/v1/verify_session");
                 if (!res.ok) throw new Error("Invalid session");
                 setLoggedIn(true);
             } catch {
@@ -15,7 +15,7 @@ const AuthForm = (props: Props) => {
 
     const handleSubmit = async (e: Event) => {
         e.preventDefault();
-        const endpoint = isSignup() ? "/api/signup" : "/api/login";
+        const endpoint = isSignup() ? "/api/v1/signup" : "/api/v1/login";
 
         try {
             const res = await fetch(endpoint, {
@@ -16,7 +16,7 @@ const ClanMenu = (props: Props) => {
 
     const fetchCurrentUser = async () => {
         try {
-            const res = await apiFetch("/api/info/current_user", {
+            const res = await apiFetch("/api/v1/info/current_user", {
                 method: "GET",
             });
             if (!res.ok) throw new Error("Failed to fetch current user");
@@ -29,7 +29,7 @@ const ClanMenu = (props: Props) => {
     };
 
     const fetchCurrentClan = async () => {
-        const res = await apiFetch("/api/info/current_clan", {
+        const res = await apiFetch("/api/v1/info/current_clan", {
             method: "GET",
         });
 
@@ -41,7 +41,7 @@ const ClanMenu = (props: Props) => {
 
         setCurrentClan(null);
 
-        if (res.status === 417) {
+        if (res.status === 404) {
             setError("");
         } else {
             setError("Failed to fetch current clan info");
@@ -54,7 +54,7 @@ const ClanMenu = (props: Props) => {
 
     const handleCreateClan = async () => {
         try {
-            const res = await apiFetch("/api/clan/create", {
+            const res = await apiFetch("/api/v1/clan/create", {
                 method: "POST",
                 headers: {
                     "Content-Type": "application/json",
@@ -76,7 +76,7 @@ const ClanMenu = (props: Props) => {
 
     const handleDeleteClan = async () => {
         try {
-            const res = await apiFetch("/api/clan/delete", {
+            const res = await apiFetch("/api/v1/clan/delete", {
                 method: "DELETE",
             });
             if (!res.ok) throw new Error("Failed to delete clan");
@@ -90,7 +90,7 @@ const ClanMenu = (props: Props) => {
 
     const handleJoinClan = async () => {
         try {
-            const res = await apiFetch("/api/clan/join", {
+            const res = await apiFetch("/api/v1/clan/join", {
                 method: "POST",
                 headers: {
                     "Content-Type": "application/json",
@@ -113,7 +113,7 @@ const ClanMenu = (props: Props) => {
 
     const handleLeaveClan = async () => {
         try {
-            const res = await apiFetch("/api/clan/leave", {
+            const res = await apiFetch("/api/v1/clan/leave", {
                 method: "POST",
             });
             if (!res.ok) throw new Error("Failed to leave clan");
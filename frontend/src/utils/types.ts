@@ -1,12 +1,83 @@
 export class User {
     id: number = 0;
     username: string = "";
-    password: string = "";
 }
 
 export class Clan {
     id: number = 0;
     name: string = "";
-    password: string = "";
     owner_id: number = 0;
-}
\ No newline at end of file
+    join_policy: string = "closed";
+}
+
+export class WeaponDefinition {
+    id: number = 0;
+    key: string = "";
+    name: string = "";
+    description: string = "";
+    icon: string = "";
+    damage: number[] = [];
+    range: number = 0;
+    cooldown: number = 0;
+    projectile?: string;
+    aoe?: number;
+}
+
+export class EvolutionDefinition {
+    weapon: string = "";
+    passive: string = "";
+    into: string = "";
+}
+
+export class EnemyDefinition {
+    key: string = "";
+    name: string = "";
+    description: string = "";
+    icon: string = "";
+    hp: number = 0;
+    speed: number = 0;
+    size: number = 0;
+    damage: number = 0;
+    range: number = 0;
+    cooldown: number = 0;
+}
+
+export class BossDefinition extends EnemyDefinition {
+    minute: number = 0;
+}
+
+export class PassiveDefinition {
+    key: string = "";
+    name: string = "";
+    description: string = "";
+    icon: string = "";
+    stat: string = "";
+    value: number = 0;
+    max_level: number = 0;
+}
+
+export class DifficultyDefinition {
+    key: string = "";
+    name: string = "";
+    description: string = "";
+    enemy_hp: number = 100;
+    enemy_damage: number = 100;
+    enemy_speed: number = 100;
+    spawn_rate: number = 100;
+    exp: number = 100;
+}
+
+export class CurseDefinition {
+    key: string = "";
+    name: string = "";
+    description: string = "";
+    enemy_speed?: number;
+    spawn_rate?: number;
+    no_healing?: boolean;
+    exp: number = 0;
+}
+
+export class RunMode {
+    difficulty: string = "";
+    curses: string[] = [];
+}
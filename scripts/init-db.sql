@@ -1,3 +1,11 @@
+DROP TABLE IF EXISTS friendships;
+DROP TABLE IF EXISTS chat_ignores;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS clan_audit_log;
+DROP TABLE IF EXISTS clan_invites;
+DROP TABLE IF EXISTS runs;
+DROP TABLE IF EXISTS player_passives;
+DROP TABLE IF EXISTS weapon_evolutions;
 DROP TABLE IF EXISTS weapons;
 DROP TABLE IF EXISTS weapon_classes;
 DROP TABLE IF EXISTS players;
@@ -15,6 +23,7 @@ CREATE TABLE clans (
     name VARCHAR(255) NOT NULL,
     password VARCHAR(255) NOT NULL,
     owner_id INTEGER NOT NULL,
+    join_policy VARCHAR(16) NOT NULL DEFAULT 'closed',
     PRIMARY KEY(id),
     FOREIGN KEY(owner_id) REFERENCES users(id),
     UNIQUE(name)
@@ -29,15 +38,79 @@ CREATE TABLE players (
     color CHAR(7) NOT NULL DEFAULT '#FF0000',
     texture_path VARCHAR(255),
     experience_points INTEGER NOT NULL DEFAULT 0,
+    -- last level a level-up offer was made for, and the offer left to choose from
+    upgrade_level INTEGER NOT NULL DEFAULT 1,
+    upgrade_offer JSONB NULL DEFAULT NULL,
     clan_id INTEGER NULL DEFAULT NULL,
+    clan_role VARCHAR(16) NULL DEFAULT NULL,
     PRIMARY KEY(id),
     FOREIGN KEY(user_id) REFERENCES users(id),
     FOREIGN KEY(clan_id) REFERENCES clans(id)
 );
+CREATE TABLE clan_invites (
+    id SERIAL,
+    clan_id INTEGER NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    created_by INTEGER NOT NULL,
+    uses_left INTEGER NULL DEFAULT NULL,
+    expires_at TIMESTAMPTZ NULL DEFAULT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(id),
+    FOREIGN KEY(clan_id) REFERENCES clans(id) ON DELETE CASCADE,
+    FOREIGN KEY(created_by) REFERENCES users(id),
+    UNIQUE(code)
+);
+CREATE TABLE clan_audit_log (
+    id SERIAL,
+    clan_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    target_id INTEGER NULL DEFAULT NULL,
+    action VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(id),
+    FOREIGN KEY(clan_id) REFERENCES clans(id) ON DELETE CASCADE,
+    FOREIGN KEY(actor_id) REFERENCES users(id),
+    FOREIGN KEY(target_id) REFERENCES users(id)
+);
+CREATE TABLE chat_messages (
+    id SERIAL,
+    channel VARCHAR(16) NOT NULL,
+    sender_id INTEGER NOT NULL,
+    clan_id INTEGER NULL DEFAULT NULL,
+    recipient_id INTEGER NULL DEFAULT NULL,
+    text VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(id),
+    FOREIGN KEY(sender_id) REFERENCES users(id),
+    FOREIGN KEY(clan_id) REFERENCES clans(id) ON DELETE CASCADE,
+    FOREIGN KEY(recipient_id) REFERENCES users(id)
+);
+CREATE INDEX chat_messages_channel_idx ON chat_messages (channel, created_at);
+CREATE TABLE chat_ignores (
+    user_id INTEGER NOT NULL,
+    target_id INTEGER NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(user_id, target_id, kind),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(target_id) REFERENCES users(id)
+);
+CREATE TABLE friendships (
+    user_id INTEGER NOT NULL,
+    friend_id INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(user_id, friend_id),
+    FOREIGN KEY(user_id) REFERENCES users(id),
+    FOREIGN KEY(friend_id) REFERENCES users(id)
+);
 CREATE TABLE weapon_classes (
     id SERIAL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
     base_damage INTEGER NOT NULL,
     base_range FLOAT NOT NULL,
+    -- ticks between two hits
     base_rate_of_fire INTEGER NOT NULL,
     PRIMARY KEY(id)
 );
@@ -50,14 +123,53 @@ CREATE TABLE weapons (
     FOREIGN KEY(weapon_class_id) REFERENCES weapon_classes(id),
     FOREIGN KEY(player_id) REFERENCES players(id)
 );
-INSERT INTO weapon_classes (base_damage, base_range, base_rate_of_fire)
-VALUES (75, 1.0, 6),
-    -- Katana: one hit every 75 ticks
-    (25, 10.0, 2),
-    -- Pistol: one hit every 45 ticks
-    (90, 7.5, 5),
-    -- Shotgun: one hit every 45 ticks
-    (55, 20.0, 3),
-    -- Rifle: one hit every 20 ticks
-    (120, 15.0, 10);
--- Hand Grenade: one hit every 75 ticks
\ No newline at end of file
+-- A weapon evolves once, its row then references the evolved class
+CREATE TABLE weapon_evolutions (
+    weapon_id INTEGER NOT NULL,
+    from_weapon_class_id INTEGER NOT NULL,
+    to_weapon_class_id INTEGER NOT NULL,
+    evolved_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(weapon_id),
+    FOREIGN KEY(weapon_id) REFERENCES weapons(id),
+    FOREIGN KEY(from_weapon_class_id) REFERENCES weapon_classes(id),
+    FOREIGN KEY(to_weapon_class_id) REFERENCES weapon_classes(id)
+);
+CREATE TABLE player_passives (
+    player_id INTEGER NOT NULL,
+    -- key of the passive in backend/data/passives.json
+    passive_key VARCHAR(64) NOT NULL,
+    level INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY(player_id, passive_key),
+    FOREIGN KEY(player_id) REFERENCES players(id)
+);
+-- Runs ended by the player's final death, with the map they were played on
+CREATE TABLE runs (
+    id SERIAL,
+    player_id INTEGER NOT NULL,
+    ticks BIGINT NOT NULL,
+    seconds FLOAT NOT NULL,
+    kills INTEGER NOT NULL,
+    experience_points INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    -- seed of a generated map, 0 when it was loaded from the layout file
+    map_seed BIGINT NOT NULL,
+    map_layout VARCHAR(255) NOT NULL DEFAULT '',
+    -- keys of the difficulty and curses from backend/data/modes.json
+    difficulty VARCHAR(255) NOT NULL DEFAULT 'normal',
+    curses TEXT[] NOT NULL DEFAULT '{}',
+    ended_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(id),
+    FOREIGN KEY(player_id) REFERENCES players(id)
+);
+CREATE INDEX runs_difficulty_idx ON runs (difficulty, experience_points DESC);
+-- Kept in sync with backend/data/weapons.json by the server on startup
+INSERT INTO weapon_classes (id, name, base_damage, base_range, base_rate_of_fire)
+VALUES (1, 'Katana', 75, 1.0, 75),
+    (2, 'Pistol', 25, 10.0, 45),
+    (3, 'Shotgun', 90, 7.5, 45),
+    (4, 'Rifle', 55, 20.0, 20),
+    (5, 'Hand Grenade', 120, 15.0, 75),
+    (6, 'Twin Blades', 180, 1.5, 40),
+    (7, 'Hand Cannon', 110, 12.0, 35),
+    (8, 'Cluster Bomb', 280, 15.0, 70);
+SELECT setval('weapon_classes_id_seq', (SELECT MAX(id) FROM weapon_classes));
\ No newline at end of file